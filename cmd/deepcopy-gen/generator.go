@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// deepcopyPath is the import path of the deepcopy package, which generated
+// code uses for the values it can't copy without reflection.
+const deepcopyPath = "github.com/mohae/utilitybelt/deepcopy"
+
+// lockTypes are the sync types whose state is reset, not copied, the same as
+// deepcopy.Iface does.
+var lockTypes = map[string]bool{
+	"sync.Mutex":     true,
+	"sync.RWMutex":   true,
+	"sync.WaitGroup": true,
+	"sync.Once":      true,
+}
+
+// generator writes the DeepCopy and DeepCopyInto methods for the named
+// struct types of a package.
+type generator struct {
+	pkg     *types.Package
+	types   []*types.Named        // the types to generate methods for
+	gen     map[*types.Named]bool // the same, for lookups
+	imports map[string]string     // the imports the output needs: path to name
+	buf     bytes.Buffer
+	vars    map[string]int // the number of variables by prefix, for unique names
+	// inlining are the types of this package, without methods of their
+	// own, that are being copied inline.
+	inlining map[*types.Named]bool
+}
+
+func newGenerator(pkg *types.Package, named []*types.Named) *generator {
+	g := &generator{
+		pkg:      pkg,
+		types:    named,
+		gen:      make(map[*types.Named]bool, len(named)),
+		imports:  make(map[string]string),
+		inlining: make(map[*types.Named]bool),
+	}
+	for _, n := range named {
+		g.gen[n] = true
+	}
+	return g
+}
+
+// generate returns the formatted source of the file with the methods.
+func (g *generator) generate() ([]byte, error) {
+	g.buf.Reset()
+	for _, n := range g.types {
+		g.genType(n)
+	}
+	body := g.buf.String()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by deepcopy-gen. DO NOT EDIT.\n\npackage %s\n\n", g.pkg.Name())
+	writeImports(&out, g.imports)
+	out.WriteString(body)
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %s", err)
+	}
+	return src, nil
+}
+
+// writeImports writes the import declaration for imports, sorted by path
+// with the standard library first.
+func writeImports(buf *bytes.Buffer, imports map[string]string) {
+	if len(imports) == 0 {
+		return
+	}
+	var std, other []string
+	for path := range imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			other = append(other, path)
+			continue
+		}
+		std = append(std, path)
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	paths := std
+	if len(std) > 0 && len(other) > 0 {
+		paths = append(paths, "")
+	}
+	paths = append(paths, other...)
+	buf.WriteString("import (\n")
+	for _, path := range paths {
+		if path == "" {
+			buf.WriteString("\n")
+			continue
+		}
+		name := imports[path]
+		if name == path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(buf, "\t%q\n", path)
+			continue
+		}
+		fmt.Fprintf(buf, "\t%s %q\n", name, path)
+	}
+	buf.WriteString(")\n\n")
+}
+
+// qualifier returns the name types from other packages are qualified with,
+// recording the import it needs.
+func (g *generator) qualifier(pkg *types.Package) string {
+	if pkg == g.pkg {
+		return ""
+	}
+	g.imports[pkg.Path()] = pkg.Name()
+	return pkg.Name()
+}
+
+// typeString returns t as it is written in the generated code.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, g.qualifier)
+}
+
+// deepcopy returns the name the deepcopy package is imported as.
+func (g *generator) deepcopy() string {
+	g.imports[deepcopyPath] = "deepcopy"
+	return "deepcopy"
+}
+
+// newVar returns a variable name with prefix that isn't used yet.
+func (g *generator) newVar(prefix string) string {
+	g.vars[prefix]++
+	if g.vars[prefix] == 1 {
+		return prefix
+	}
+	return prefix + strconv.Itoa(g.vars[prefix]-1)
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// genType writes the methods for n.
+func (g *generator) genType(n *types.Named) {
+	name := g.typeString(n)
+	st := n.Underlying().(*types.Struct)
+	g.vars = make(map[string]int)
+
+	g.printf("// DeepCopyInto copies the receiver into out. The receiver must be non-nil.\n")
+	g.printf("func (in *%s) DeepCopyInto(out *%s) {\n", name, name)
+	if g.hasLock(st) {
+		g.genAssign(st, "in", "out")
+	} else {
+		g.printf("*out = *in\n")
+	}
+	g.genFields(st, "in", "out")
+	g.printf("}\n\n")
+
+	g.printf("// DeepCopy returns a deep copy of the receiver. A nil receiver returns nil.\n")
+	g.printf("func (in *%s) DeepCopy() *%s {\n", name, name)
+	g.printf("if in == nil {\nreturn nil\n}\n")
+	g.printf("out := new(%s)\n", name)
+	g.printf("in.DeepCopyInto(out)\n")
+	g.printf("return out\n")
+	g.printf("}\n\n")
+}
+
+// genAssign writes the shallow copy of in, of type t, into out, for types
+// that hold a lock. Assigning those copies the lock, so they are assigned
+// part by part instead, leaving out the locks, and the values of types that
+// are copied as a whole, for genCopy to set. in and out are expressions as
+// deref returns them.
+func (g *generator) genAssign(t types.Type, in, out string) {
+	if g.isLock(t) || g.isGenerated(t) || g.isExternal(t) {
+		return
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			if f.Name() == "_" {
+				continue
+			}
+			fin, fout := in+"."+f.Name(), out+"."+f.Name()
+			switch mode := tagMode(u.Tag(i)); {
+			case mode == "-" || mode == "redact":
+			case mode == "shallow" || !g.hasLock(f.Type()):
+				g.printf("%s = %s\n", fout, fin)
+			default:
+				g.genAssign(f.Type(), fin, fout)
+			}
+		}
+	case *types.Array:
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, in)
+		g.genAssign(u.Elem(), in+"["+i+"]", out+"["+i+"]")
+		g.printf("}\n")
+	}
+}
+
+// genFields writes the copying of the fields of the struct in into out,
+// which already holds a shallow copy of in.
+func (g *generator) genFields(st *types.Struct, in, out string) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		// Blank fields can't be referred to, and hold nothing.
+		if f.Name() == "_" {
+			continue
+		}
+		fin, fout := in+"."+f.Name(), out+"."+f.Name()
+		switch tagMode(st.Tag(i)) {
+		case "-", "redact":
+			g.printf("%s = %s\n", fout, g.zero(f.Type()))
+			continue
+		case "shallow":
+			continue
+		}
+		g.genCopy(f.Type(), fin, fout)
+	}
+}
+
+// tagMode returns how the deepcopy tag says a field is to be copied.
+func tagMode(tag string) string {
+	var mode string
+	for _, opt := range strings.Split(reflect.StructTag(tag).Get("deepcopy"), ",") {
+		switch opt = strings.TrimSpace(opt); opt {
+		case "-", "shallow", "redact":
+			mode = opt
+		}
+	}
+	return mode
+}
+
+// genCopy writes the deep copying of in, of type t, into out. Both are
+// addressable expressions and out already holds a shallow copy of in, so
+// only what refers to memory shared with in is written.
+func (g *generator) genCopy(t types.Type, in, out string) {
+	if !g.needsCopy(t) {
+		return
+	}
+	if g.isLock(t) {
+		g.printf("%s = %s{}\n", out, g.typeString(t))
+		return
+	}
+	if g.isGenerated(t) {
+		g.printf("%s.DeepCopyInto(&%s)\n", in, out)
+		return
+	}
+	// Types from other packages can't be copied field by field, their
+	// unexported fields aren't accessible. Nor can types that are already
+	// being copied inline, which are recursive: inlining them again would
+	// never end. Both are copied with deepcopy.Copy.
+	if g.isExternal(t) || g.isInlining(t) {
+		g.genReflectCopy(in, out)
+		return
+	}
+	if n, ok := types.Unalias(t).(*types.Named); ok {
+		g.inlining[n] = true
+		defer delete(g.inlining, n)
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		// A pointer to a type being copied inline is copied as a whole, so
+		// the copy of what it points to isn't written into a new value.
+		if g.isInlining(u.Elem()) {
+			g.genReflectCopy(in, out)
+			return
+		}
+		elem := g.typeString(u.Elem())
+		g.printf("if %s != nil {\n", in)
+		g.printf("%s = new(%s)\n", out, elem)
+		switch {
+		case g.isLock(u.Elem()):
+			// The new value is already a reset lock.
+		case g.isGenerated(u.Elem()):
+			g.printf("%s.DeepCopyInto(%s)\n", in, out)
+		case g.isExternal(u.Elem()) && g.needsCopy(u.Elem()):
+			g.genCopy(u.Elem(), "*"+in, "*"+out)
+		case g.hasLock(u.Elem()):
+			g.genAssign(u.Elem(), deref(u.Elem(), in), deref(u.Elem(), out))
+			g.genCopy(u.Elem(), deref(u.Elem(), in), deref(u.Elem(), out))
+		default:
+			g.printf("*%s = *%s\n", out, in)
+			g.genCopy(u.Elem(), deref(u.Elem(), in), deref(u.Elem(), out))
+		}
+		g.printf("}\n")
+	case *types.Slice:
+		g.printf("if %s != nil {\n", in)
+		g.printf("%s = make(%s, len(%s), cap(%s))\n", out, g.typeString(t), in, in)
+		g.printf("copy(%s, %s)\n", out, in)
+		if g.needsCopy(u.Elem()) {
+			i := g.newVar("i")
+			g.printf("for %s := range %s {\n", i, in)
+			g.genCopy(u.Elem(), in+"["+i+"]", out+"["+i+"]")
+			g.printf("}\n")
+		}
+		g.printf("}\n")
+	case *types.Array:
+		i := g.newVar("i")
+		g.printf("for %s := range %s {\n", i, in)
+		g.genCopy(u.Elem(), in+"["+i+"]", out+"["+i+"]")
+		g.printf("}\n")
+	case *types.Map:
+		key, val := g.newVar("key"), g.newVar("val")
+		g.printf("if %s != nil {\n", in)
+		g.printf("%s = make(%s, len(%s))\n", out, g.typeString(t), in)
+		g.printf("for %s, %s := range %s {\n", key, val, in)
+		if g.needsCopy(u.Elem()) {
+			cpy := g.newVar("cpy")
+			// Only structs and arrays of this package are copied in place,
+			// everything else is replaced as a whole.
+			if g.inPlace(u.Elem()) {
+				g.printf("%s := %s\n", cpy, val)
+			} else {
+				g.printf("var %s %s\n", cpy, g.typeString(u.Elem()))
+			}
+			g.genCopy(u.Elem(), val, cpy)
+			val = cpy
+		}
+		g.printf("%s[%s] = %s\n", out, key, val)
+		g.printf("}\n")
+		g.printf("}\n")
+	case *types.Interface:
+		g.printf("if %s != nil {\n", in)
+		g.genReflectCopy(in, out)
+		g.printf("}\n")
+	case *types.Struct:
+		g.genFields(u, in, out)
+	}
+}
+
+// genReflectCopy writes the copying of in into out with deepcopy.Copy.
+func (g *generator) genReflectCopy(in, out string) {
+	g.printf("%s = %s.Copy(%s, %s.CopyUnexported())\n", out, g.deepcopy(), in, g.deepcopy())
+}
+
+// isInlining reports whether t is a type that is being copied inline.
+func (g *generator) isInlining(t types.Type) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	return ok && g.inlining[n]
+}
+
+// isGenerated reports whether t is one of the types methods are generated
+// for.
+func (g *generator) isGenerated(t types.Type) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	return ok && g.gen[n]
+}
+
+// isExternal reports whether t is a named type from another package.
+func (g *generator) isExternal(t types.Type) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg() != g.pkg
+}
+
+// inPlace reports whether values of t are deep copied by fixing up a
+// shallow copy of them, rather than by assigning a new value.
+func (g *generator) inPlace(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		return !g.isGenerated(t) && !g.isExternal(t)
+	}
+	return false
+}
+
+// deref returns the expression for the value ptr, of type *t, points to.
+// Selectors and indexes work on pointers to structs and arrays, so those
+// don't need to be dereferenced.
+func deref(t types.Type, ptr string) string {
+	switch t.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		return ptr
+	}
+	return "(*" + ptr + ")"
+}
+
+// needsCopy reports whether values of t refer to memory that a shallow copy
+// would share. Channels and funcs are shared, the same as deepcopy.Iface
+// does.
+func (g *generator) needsCopy(t types.Type) bool {
+	return g.needsCopySeen(t, make(map[types.Type]bool))
+}
+
+func (g *generator) needsCopySeen(t types.Type, seen map[types.Type]bool) bool {
+	t = types.Unalias(t)
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if n, ok := t.(*types.Named); ok && g.gen[n] {
+		return true
+	}
+	if g.isLock(t) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Interface:
+		return true
+	case *types.Array:
+		return g.needsCopySeen(u.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if tagMode(u.Tag(i)) != "" || g.isLock(u.Field(i).Type()) || g.needsCopySeen(u.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isLock reports whether t is a sync type whose state isn't copied.
+func (g *generator) isLock(t types.Type) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	if !ok || n.Obj().Pkg() == nil {
+		return false
+	}
+	return lockTypes[n.Obj().Pkg().Path()+"."+n.Obj().Name()]
+}
+
+// hasLock reports whether values of t hold a lock, in themselves or in the
+// structs and arrays within them, the same way go vet's copylocks check
+// finds them.
+func (g *generator) hasLock(t types.Type) bool {
+	if g.isLock(t) {
+		return true
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if g.hasLock(u.Field(i).Type()) {
+				return true
+			}
+		}
+	case *types.Array:
+		return g.hasLock(u.Elem())
+	}
+	return false
+}
+
+// zero returns the zero value of t as it is written in Go.
+func (g *generator) zero(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Kind() == types.UnsafePointer:
+			return "nil"
+		}
+		return "0"
+	case *types.Struct, *types.Array:
+		return g.typeString(t) + "{}"
+	}
+	return "nil"
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// The generated files for testdata/example are kept with it, and their test,
+// which checks the generated methods against deepcopy.Iface, is run on what
+// is generated.
+func TestGenerate(t *testing.T) {
+	const example = "testdata/example"
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join(example, "example.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "example.go"), src, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(dir, "zz_generated_deepcopy.go", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"zz_generated_deepcopy.go", "zz_generated_deepcopy_test.go"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join(example, name)
+		if *update {
+			err = os.WriteFile(golden, got, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s doesn't match %s; run the tests with -update if the change is expected", name, golden)
+		}
+	}
+	goTest(t, dir)
+}
+
+// Types that methods aren't generated for are copied inline, except when
+// they are recursive, with the locks within them reset.
+func TestGenerateRecursive(t *testing.T) {
+	const src = `package example
+
+import "sync"
+
+type A struct {
+	L    *List
+	T    Tree
+	Refs map[string]Tree
+	G    Guarded
+	P    *Guarded
+}
+
+type Guarded struct {
+	mu   sync.Mutex
+	Data []int
+}
+
+type List struct {
+	V    int
+	Next *List
+}
+
+type Tree struct {
+	Name string
+	Kids []Tree
+}
+`
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "example.go"), []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = run(dir, "zz_generated_deepcopy.go", []string{"A"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	goTest(t, dir)
+}
+
+// goTest runs go vet and go test on the package in dir, which has the
+// generated files.
+func goTest(t *testing.T, dir string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping go test of the generated files in short mode")
+	}
+	for _, command := range []string{"vet", "test"} {
+		// The generated files import deepcopy by its GOPATH import path.
+		cmd := exec.Command("go", command, ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GO111MODULE=off")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("go %s of the generated files failed: %s\n%s", command, err, out)
+		}
+	}
+}
+
+func TestStructTypes(t *testing.T) {
+	pkg, err := loadPackage("testdata/example", "zz_generated_deepcopy.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		names    []string
+		expected []string
+		err      string
+	}{
+		{nil, []string{"Backend", "Logger", "Server"}, ""},
+		{[]string{"Server", " Backend"}, []string{"Backend", "Server"}, ""},
+		{[]string{"Hosts"}, nil, "Hosts: not a non-generic struct type"},
+		{[]string{"Client"}, nil, "Client: type not found"},
+	}
+	for i, test := range tests {
+		named, err := structTypes(pkg, test.names)
+		if err != nil {
+			if err.Error() != test.err {
+				t.Errorf("%d: expected error %q, got %q", i, test.err, err)
+			}
+			continue
+		}
+		if test.err != "" {
+			t.Errorf("%d: expected error %q, got none", i, test.err)
+			continue
+		}
+		if len(named) != len(test.expected) {
+			t.Errorf("%d: expected %v, got %v", i, test.expected, named)
+			continue
+		}
+		for j, n := range named {
+			if n.Obj().Name() != test.expected[j] {
+				t.Errorf("%d: expected %s, got %s", i, test.expected[j], n.Obj().Name())
+			}
+		}
+	}
+}
+
+func TestTestName(t *testing.T) {
+	if name := testName("zz_generated_deepcopy.go"); name != "zz_generated_deepcopy_test.go" {
+		t.Errorf("expected zz_generated_deepcopy_test.go, got %s", name)
+	}
+}
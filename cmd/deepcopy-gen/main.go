@@ -0,0 +1,157 @@
+// Command deepcopy-gen generates DeepCopy and DeepCopyInto methods for the
+// struct types of a package, so values that are copied often can be copied
+// without reflection.
+//
+// The generated methods copy the same way deepcopy.Iface does with the
+// deepcopy.CopyUnexported option: pointers, slices, maps, arrays, and
+// nested structs are deep copied, with nil slices and maps staying nil;
+// interfaces are copied with deepcopy.Copy; channels and funcs are shared;
+// sync.Mutex and the other sync primitives are reset; deepcopy struct tags
+// are honored. Unlike Iface, the generated methods don't detect cycles: they
+// are meant for tree shaped values. Recursive types that methods aren't
+// generated for are copied with deepcopy.Copy.
+//
+// Usage:
+//
+//	deepcopy-gen [flags] [dir]
+//
+// The package in dir, the current directory by default, is read and the
+// methods are written to zz_generated_deepcopy.go within it. The flags are:
+//
+//	-type    a comma separated list of the types to generate methods for;
+//	         by default, every struct type in the package
+//	-output  the name of the file to write
+//	-verify  also write a test, to the output's _test.go file, that checks
+//	         the generated methods copy the same way deepcopy.Iface does
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma separated list of the types to generate methods for; by default all struct types")
+	output    = flag.String("output", "zz_generated_deepcopy.go", "the name of the file to write")
+	verify    = flag.Bool("verify", false, "also write a test that checks the generated methods against deepcopy.Iface")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: deepcopy-gen [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	var names []string
+	if *typeNames != "" {
+		names = strings.Split(*typeNames, ",")
+	}
+	err := run(dir, *output, names, *verify)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "deepcopy-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the methods for the package in dir and writes them to the
+// output file within it.
+func run(dir, output string, names []string, verify bool) error {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return err
+	}
+	named, err := structTypes(pkg, names)
+	if err != nil {
+		return err
+	}
+	src, err := newGenerator(pkg, named).generate()
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(dir, output), src, 0644)
+	if err != nil {
+		return err
+	}
+	if !verify {
+		return nil
+	}
+	src, err = generateVerify(pkg, named)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, testName(output)), src, 0644)
+}
+
+// testName returns the name of the test file that goes with output.
+func testName(output string) string {
+	return strings.TrimSuffix(output, ".go") + "_test.go"
+}
+
+// loadPackage parses and type checks the package in dir. The output of an
+// earlier run is skipped: it may not compile once the types have changed.
+func loadPackage(dir, output string) (*types.Package, error) {
+	bpkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bpkg.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(bpkg.Name, fset, files, nil)
+}
+
+// structTypes returns the named struct types of pkg that are in names, or
+// all of them if names is empty, sorted by name. Generic types are not
+// supported.
+func structTypes(pkg *types.Package, names []string) ([]*types.Named, error) {
+	scope := pkg.Scope()
+	explicit := len(names) > 0
+	if !explicit {
+		names = scope.Names()
+	}
+	var named []*types.Named
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || obj.IsAlias() {
+			if explicit {
+				return nil, fmt.Errorf("%s: type not found", name)
+			}
+			continue
+		}
+		n := obj.Type().(*types.Named)
+		if _, ok := n.Underlying().(*types.Struct); !ok || n.TypeParams().Len() > 0 {
+			if explicit {
+				return nil, fmt.Errorf("%s: not a non-generic struct type", name)
+			}
+			continue
+		}
+		named = append(named, n)
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].Obj().Name() < named[j].Obj().Name() })
+	return named, nil
+}
@@ -0,0 +1,62 @@
+// Package example has the types the deepcopy-gen tests generate methods for.
+package example
+
+import (
+	"math/big"
+	"sync"
+	"time"
+)
+
+// Server is a struct with all the kinds of fields that need deep copying.
+type Server struct {
+	Name     string
+	Port     int
+	Tags     []string
+	Labels   map[string]string
+	Backends []*Backend
+	ByName   map[string]*Backend
+	Primary  *Backend
+	Fallback Backend
+	Weights  [3]*int
+	Meta     interface{}
+	Started  time.Time
+	Count    *big.Int
+	Limits   struct {
+		Rate  int
+		Burst *int
+	}
+	Matrix   [][]int
+	Nested   map[string][]map[string]int
+	Cache    map[string]int `deepcopy:"-"`
+	Password string         `deepcopy:"redact"`
+	Logger   *Logger        `deepcopy:"shallow"`
+	Done     chan struct{}
+	OnStop   func()
+	conns    []int
+	mu       sync.Mutex
+	_        [0]func()
+	State    struct {
+		mu   sync.RWMutex
+		Hits []int
+	}
+	Shards [2]struct {
+		sync.Mutex
+		N *int
+	}
+}
+
+// Backend is used by Server.
+type Backend struct {
+	Addr    string
+	Healthy bool
+	Next    *Backend
+	Hosts   Hosts
+}
+
+// Hosts is a named slice type.
+type Hosts []string
+
+// Logger is shared by copies.
+type Logger struct {
+	Prefix string
+}
@@ -0,0 +1,187 @@
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/mohae/utilitybelt/deepcopy"
+)
+
+// DeepCopyInto copies the receiver into out. The receiver must be non-nil.
+func (in *Backend) DeepCopyInto(out *Backend) {
+	*out = *in
+	if in.Next != nil {
+		out.Next = new(Backend)
+		in.Next.DeepCopyInto(out.Next)
+	}
+	if in.Hosts != nil {
+		out.Hosts = make(Hosts, len(in.Hosts), cap(in.Hosts))
+		copy(out.Hosts, in.Hosts)
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver. A nil receiver returns nil.
+func (in *Backend) DeepCopy() *Backend {
+	if in == nil {
+		return nil
+	}
+	out := new(Backend)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. The receiver must be non-nil.
+func (in *Logger) DeepCopyInto(out *Logger) {
+	*out = *in
+}
+
+// DeepCopy returns a deep copy of the receiver. A nil receiver returns nil.
+func (in *Logger) DeepCopy() *Logger {
+	if in == nil {
+		return nil
+	}
+	out := new(Logger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out. The receiver must be non-nil.
+func (in *Server) DeepCopyInto(out *Server) {
+	out.Name = in.Name
+	out.Port = in.Port
+	out.Tags = in.Tags
+	out.Labels = in.Labels
+	out.Backends = in.Backends
+	out.ByName = in.ByName
+	out.Primary = in.Primary
+	out.Fallback = in.Fallback
+	out.Weights = in.Weights
+	out.Meta = in.Meta
+	out.Started = in.Started
+	out.Count = in.Count
+	out.Limits = in.Limits
+	out.Matrix = in.Matrix
+	out.Nested = in.Nested
+	out.Logger = in.Logger
+	out.Done = in.Done
+	out.OnStop = in.OnStop
+	out.conns = in.conns
+	out.State.Hits = in.State.Hits
+	for i := range in.Shards {
+		out.Shards[i].N = in.Shards[i].N
+	}
+	if in.Tags != nil {
+		out.Tags = make([]string, len(in.Tags), cap(in.Tags))
+		copy(out.Tags, in.Tags)
+	}
+	if in.Labels != nil {
+		out.Labels = make(map[string]string, len(in.Labels))
+		for key, val := range in.Labels {
+			out.Labels[key] = val
+		}
+	}
+	if in.Backends != nil {
+		out.Backends = make([]*Backend, len(in.Backends), cap(in.Backends))
+		copy(out.Backends, in.Backends)
+		for i1 := range in.Backends {
+			if in.Backends[i1] != nil {
+				out.Backends[i1] = new(Backend)
+				in.Backends[i1].DeepCopyInto(out.Backends[i1])
+			}
+		}
+	}
+	if in.ByName != nil {
+		out.ByName = make(map[string]*Backend, len(in.ByName))
+		for key1, val1 := range in.ByName {
+			var cpy *Backend
+			if val1 != nil {
+				cpy = new(Backend)
+				val1.DeepCopyInto(cpy)
+			}
+			out.ByName[key1] = cpy
+		}
+	}
+	if in.Primary != nil {
+		out.Primary = new(Backend)
+		in.Primary.DeepCopyInto(out.Primary)
+	}
+	in.Fallback.DeepCopyInto(&out.Fallback)
+	for i2 := range in.Weights {
+		if in.Weights[i2] != nil {
+			out.Weights[i2] = new(int)
+			*out.Weights[i2] = *in.Weights[i2]
+		}
+	}
+	if in.Meta != nil {
+		out.Meta = deepcopy.Copy(in.Meta, deepcopy.CopyUnexported())
+	}
+	out.Started = deepcopy.Copy(in.Started, deepcopy.CopyUnexported())
+	if in.Count != nil {
+		out.Count = new(big.Int)
+		*out.Count = deepcopy.Copy(*in.Count, deepcopy.CopyUnexported())
+	}
+	if in.Limits.Burst != nil {
+		out.Limits.Burst = new(int)
+		*out.Limits.Burst = *in.Limits.Burst
+	}
+	if in.Matrix != nil {
+		out.Matrix = make([][]int, len(in.Matrix), cap(in.Matrix))
+		copy(out.Matrix, in.Matrix)
+		for i3 := range in.Matrix {
+			if in.Matrix[i3] != nil {
+				out.Matrix[i3] = make([]int, len(in.Matrix[i3]), cap(in.Matrix[i3]))
+				copy(out.Matrix[i3], in.Matrix[i3])
+			}
+		}
+	}
+	if in.Nested != nil {
+		out.Nested = make(map[string][]map[string]int, len(in.Nested))
+		for key2, val2 := range in.Nested {
+			var cpy1 []map[string]int
+			if val2 != nil {
+				cpy1 = make([]map[string]int, len(val2), cap(val2))
+				copy(cpy1, val2)
+				for i4 := range val2 {
+					if val2[i4] != nil {
+						cpy1[i4] = make(map[string]int, len(val2[i4]))
+						for key3, val3 := range val2[i4] {
+							cpy1[i4][key3] = val3
+						}
+					}
+				}
+			}
+			out.Nested[key2] = cpy1
+		}
+	}
+	out.Cache = nil
+	out.Password = ""
+	if in.conns != nil {
+		out.conns = make([]int, len(in.conns), cap(in.conns))
+		copy(out.conns, in.conns)
+	}
+	out.mu = sync.Mutex{}
+	out.State.mu = sync.RWMutex{}
+	if in.State.Hits != nil {
+		out.State.Hits = make([]int, len(in.State.Hits), cap(in.State.Hits))
+		copy(out.State.Hits, in.State.Hits)
+	}
+	for i5 := range in.Shards {
+		out.Shards[i5].Mutex = sync.Mutex{}
+		if in.Shards[i5].N != nil {
+			out.Shards[i5].N = new(int)
+			*out.Shards[i5].N = *in.Shards[i5].N
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver. A nil receiver returns nil.
+func (in *Server) DeepCopy() *Server {
+	if in == nil {
+		return nil
+	}
+	out := new(Server)
+	in.DeepCopyInto(out)
+	return out
+}
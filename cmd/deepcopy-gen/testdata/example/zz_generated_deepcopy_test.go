@@ -0,0 +1,143 @@
+// Code generated by deepcopy-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/mohae/utilitybelt/deepcopy"
+)
+
+func TestGeneratedDeepCopy(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		{
+			in := new(Backend)
+			fillGenerated(reflect.ValueOf(in).Elem(), r, 0)
+			verifyGenerated(t, "Backend", in, in.DeepCopy())
+		}
+		{
+			in := new(Logger)
+			fillGenerated(reflect.ValueOf(in).Elem(), r, 0)
+			verifyGenerated(t, "Logger", in, in.DeepCopy())
+		}
+		{
+			in := new(Server)
+			fillGenerated(reflect.ValueOf(in).Elem(), r, 0)
+			verifyGenerated(t, "Server", in, in.DeepCopy())
+		}
+	}
+}
+
+// verifyGenerated checks that got, the result of a generated DeepCopy of
+// in, is what deepcopy.Iface makes of in.
+func verifyGenerated(t *testing.T, name string, in, got interface{}) {
+	t.Helper()
+	want, err := deepcopy.IfaceE(in, deepcopy.CopyUnexported(), deepcopy.OnUncopyable(deepcopy.Share))
+	if err != nil {
+		t.Fatalf("%s: %s", name, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: DeepCopy doesn't match deepcopy.Iface\ngot:  %#v\nwant: %#v", name, got, want)
+	}
+}
+
+// generatedPkg is used to get the path of this package.
+type generatedPkg struct{}
+
+// generatedHeld is one of the types empty interfaces are filled with.
+type generatedHeld struct {
+	N      int
+	P      *int
+	secret []string
+}
+
+// fillGenerated sets v to random values. Empty interfaces hold one of a few
+// types; other interfaces, channels, and funcs are left nil, as are the
+// unexported fields of types from other packages. Mutexes may be locked.
+func fillGenerated(v reflect.Value, r *rand.Rand, depth int) {
+	if !v.CanSet() {
+		v = reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(r.Int63n(100))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(r.Int63n(100)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(r.Float64())
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(complex(r.Float64(), r.Float64()))
+	case reflect.String:
+		v.SetString(string(rune('a' + r.Intn(26))))
+	case reflect.Ptr:
+		if depth < 4 && r.Intn(4) != 0 {
+			v.Set(reflect.New(v.Type().Elem()))
+			fillGenerated(v.Elem(), r, depth+1)
+		}
+	case reflect.Slice:
+		if depth < 4 && r.Intn(4) != 0 {
+			n := r.Intn(4)
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+			for i := 0; i < n; i++ {
+				fillGenerated(v.Index(i), r, depth+1)
+			}
+		}
+	case reflect.Map:
+		if depth < 4 && r.Intn(4) != 0 {
+			m := reflect.MakeMap(v.Type())
+			for i := r.Intn(4); i > 0; i-- {
+				key := reflect.New(v.Type().Key()).Elem()
+				fillGenerated(key, r, depth+1)
+				val := reflect.New(v.Type().Elem()).Elem()
+				fillGenerated(val, r, depth+1)
+				m.SetMapIndex(key, val)
+			}
+			v.Set(m)
+		}
+	case reflect.Interface:
+		if v.NumMethod() == 0 && depth < 4 && r.Intn(4) != 0 {
+			held := []reflect.Type{
+				reflect.TypeOf(0),
+				reflect.TypeOf(""),
+				reflect.TypeOf([]int(nil)),
+				reflect.TypeOf(map[string]*int(nil)),
+				reflect.TypeOf(generatedHeld{}),
+				reflect.TypeOf(&generatedHeld{}),
+			}
+			e := reflect.New(held[r.Intn(len(held))]).Elem()
+			fillGenerated(e, r, depth+1)
+			v.Set(e)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fillGenerated(v.Index(i), r, depth)
+		}
+	case reflect.Struct:
+		switch m := v.Addr().Interface().(type) {
+		case *sync.Mutex:
+			if r.Intn(2) == 0 {
+				m.Lock()
+			}
+			return
+		case *sync.RWMutex:
+			if r.Intn(2) == 0 {
+				m.Lock()
+			}
+			return
+		}
+		pkg := reflect.TypeOf(generatedPkg{}).PkgPath()
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() && v.Type().PkgPath() != pkg {
+				continue
+			}
+			fillGenerated(v.Field(i), r, depth)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package deepcopy
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CoerceOption configures how values are converted by ToStrings, ToInts,
+// ToInt64s, ToFloats, and ToBools.
+type CoerceOption func(*coercion)
+
+// coercion holds the settings for a single conversion to a slice.
+type coercion struct {
+	lenient bool
+}
+
+// Lenient also converts values of other kinds when nothing is lost: strings
+// are parsed, e.g. "42" to 42 and "true" to true; floats with no fraction
+// become integers, e.g. 3.0 to 3; numbers and bools are formatted as
+// strings; and the numbers 0 and 1 become bools.
+func Lenient() CoerceOption {
+	return func(c *coercion) {
+		c.lenient = true
+	}
+}
+
+// ToStrings returns a copy of v as a []string. v is a slice or array, whose
+// elements may be held in interfaces, or a single value. Without Lenient,
+// only values whose kind is string are converted. Any other value results
+// in a *ConvertError; a nil v results in a nil slice.
+func ToStrings(v interface{}, opts ...CoerceOption) ([]string, error) {
+	return toSlice(v, opts, func(e reflect.Value, lenient bool) (string, bool) {
+		switch {
+		case e.Kind() == reflect.String:
+			return e.String(), true
+		case !lenient:
+			return "", false
+		case e.Kind() == reflect.Bool:
+			return strconv.FormatBool(e.Bool()), true
+		case isInt(e.Type()):
+			return strconv.FormatInt(e.Int(), 10), true
+		case isUint(e.Type()):
+			return strconv.FormatUint(e.Uint(), 10), true
+		case isFloat(e.Type()):
+			return strconv.FormatFloat(e.Float(), 'g', -1, e.Type().Bits()), true
+		}
+		return "", false
+	})
+}
+
+// ToInts returns a copy of v as an []int. v is a slice or array, whose
+// elements may be held in interfaces, or a single value. Without Lenient,
+// only integers that fit in an int are converted. Any other value results
+// in a *ConvertError; a nil v results in a nil slice.
+func ToInts(v interface{}, opts ...CoerceOption) ([]int, error) {
+	return toSlice(v, opts, func(e reflect.Value, lenient bool) (int, bool) {
+		n, ok := coerceNumber(e, reflect.TypeOf(0), lenient)
+		return int(n.Int()), ok
+	})
+}
+
+// ToInt64s returns a copy of v as an []int64. v is a slice or array, whose
+// elements may be held in interfaces, or a single value. Without Lenient,
+// only integers that fit in an int64 are converted. Any other value results
+// in a *ConvertError; a nil v results in a nil slice.
+func ToInt64s(v interface{}, opts ...CoerceOption) ([]int64, error) {
+	return toSlice(v, opts, func(e reflect.Value, lenient bool) (int64, bool) {
+		n, ok := coerceNumber(e, reflect.TypeOf(int64(0)), lenient)
+		return n.Int(), ok
+	})
+}
+
+// ToFloats returns a copy of v as a []float64. v is a slice or array, whose
+// elements may be held in interfaces, or a single value. Without Lenient,
+// only numbers that a float64 holds exactly are converted. Any other value
+// results in a *ConvertError; a nil v results in a nil slice.
+func ToFloats(v interface{}, opts ...CoerceOption) ([]float64, error) {
+	return toSlice(v, opts, func(e reflect.Value, lenient bool) (float64, bool) {
+		n, ok := coerceNumber(e, reflect.TypeOf(0.0), lenient)
+		return n.Float(), ok
+	})
+}
+
+// ToBools returns a copy of v as a []bool. v is a slice or array, whose
+// elements may be held in interfaces, or a single value. Without Lenient,
+// only values whose kind is bool are converted. Any other value results in
+// a *ConvertError; a nil v results in a nil slice.
+func ToBools(v interface{}, opts ...CoerceOption) ([]bool, error) {
+	return toSlice(v, opts, func(e reflect.Value, lenient bool) (bool, bool) {
+		switch {
+		case e.Kind() == reflect.Bool:
+			return e.Bool(), true
+		case !lenient:
+			return false, false
+		case e.Kind() == reflect.String:
+			b, err := strconv.ParseBool(strings.TrimSpace(e.String()))
+			return b, err == nil
+		case isNumber(e.Type()):
+			n, ok := convertNumber(e, reflect.TypeOf(0))
+			if ok && (n.Int() == 0 || n.Int() == 1) {
+				return n.Int() == 1, true
+			}
+		}
+		return false, false
+	})
+}
+
+// toSlice converts v, a slice, an array, or a single value, to a []T with
+// conv, which reports whether it could convert an element.
+func toSlice[T any](v interface{}, opts []CoerceOption, conv func(e reflect.Value, lenient bool) (T, bool)) ([]T, error) {
+	var c coercion
+	for _, opt := range opts {
+		opt(&c)
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || (rv.Kind() == reflect.Slice && rv.IsNil()) {
+		return nil, nil
+	}
+	typ := reflect.TypeOf([]T(nil))
+	convert := func(e reflect.Value, path Path) (T, error) {
+		for e.Kind() == reflect.Interface || e.Kind() == reflect.Ptr {
+			if e.IsNil() {
+				break
+			}
+			e = e.Elem()
+		}
+		t, ok := conv(e, c.lenient)
+		if !ok {
+			return t, &ConvertError{Type: typ, Path: path, From: e.Type(), To: typ.Elem()}
+		}
+		return t, nil
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		t, err := convert(rv, nil)
+		if err != nil {
+			return nil, err
+		}
+		return []T{t}, nil
+	}
+	out := make([]T, rv.Len())
+	for i := range out {
+		var err error
+		out[i], err = convert(rv.Index(i), Path{{Kind: IndexStep, Index: i}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// coerceNumber converts e to the number type t. Without lenient, only
+// integers are converted to integer types and numbers to float types, and
+// only if they fit.
+func coerceNumber(e reflect.Value, t reflect.Type, lenient bool) (reflect.Value, bool) {
+	zero := reflect.Zero(t)
+	switch {
+	case isInt(e.Type()) || isUint(e.Type()) || (isFloat(e.Type()) && (lenient || isFloat(t))):
+		n, ok := convertNumber(e, t)
+		if !ok {
+			return zero, false
+		}
+		return n, true
+	case lenient && e.Kind() == reflect.String:
+		s := strings.TrimSpace(e.String())
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return coerceNumber(reflect.ValueOf(n), t, lenient)
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return coerceNumber(reflect.ValueOf(f), t, lenient)
+		}
+	}
+	return zero, false
+}
@@ -0,0 +1,119 @@
+package deepcopy
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestToInts(t *testing.T) {
+	var decoded interface{}
+	err := json.Unmarshal([]byte(`[1, 2, 3.0]`), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		v        interface{}
+		lenient  bool
+		expected []int
+		err      bool
+	}{
+		{v: nil},
+		{v: []int(nil)},
+		{v: []int{1, 2}, expected: []int{1, 2}},
+		{v: [2]int8{1, -2}, expected: []int{1, -2}},
+		{v: 5, expected: []int{5}},
+		{v: []interface{}{1, int64(2), uint8(3)}, expected: []int{1, 2, 3}},
+		{v: []interface{}{1, "2"}, err: true},
+		{v: []interface{}{1, "2"}, lenient: true, expected: []int{1, 2}},
+		{v: decoded, err: true},
+		{v: decoded, lenient: true, expected: []int{1, 2, 3}},
+		{v: []interface{}{3.5}, lenient: true, err: true},
+		{v: []interface{}{" 42 ", "1e3"}, lenient: true, expected: []int{42, 1000}},
+		{v: []interface{}{"x"}, lenient: true, err: true},
+		{v: []interface{}{nil}, lenient: true, err: true},
+		{v: []uint64{1 << 63}, err: true},
+		{v: map[string]int{}, err: true},
+	}
+	for i, test := range tests {
+		var opts []CoerceOption
+		if test.lenient {
+			opts = append(opts, Lenient())
+		}
+		got, err := ToInts(test.v, opts...)
+		if (err != nil) != test.err {
+			t.Errorf("%d: expected error %t, got %v", i, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%d: expected %#v, got %#v", i, test.expected, got)
+		}
+	}
+
+	_, err = ToInts([]interface{}{1, "2"})
+	var cerr *ConvertError
+	if !errors.As(err, &cerr) || cerr.Path.String() != "[1]" || cerr.From.Kind() != reflect.String {
+		t.Errorf("expected a ConvertError at [1], got %v", err)
+	}
+}
+
+func TestToInt64s(t *testing.T) {
+	got, err := ToInt64s([]interface{}{int32(1), uint32(2), "3"}, Lenient())
+	if err != nil || !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v %v", got, err)
+	}
+	if _, err := ToInt64s(1.0); err == nil {
+		t.Error("expected a float to need Lenient")
+	}
+}
+
+func TestToFloats(t *testing.T) {
+	got, err := ToFloats([]interface{}{1, float32(0.5), 2.25})
+	if err != nil || !reflect.DeepEqual(got, []float64{1, 0.5, 2.25}) {
+		t.Errorf("expected [1 0.5 2.25], got %v %v", got, err)
+	}
+	if _, err := ToFloats(int64(1<<53 + 1)); err == nil {
+		t.Error("expected an int64 a float64 can't hold to fail")
+	}
+	if _, err := ToFloats("1.5"); err == nil {
+		t.Error("expected a string to need Lenient")
+	}
+	got, err = ToFloats("1.5", Lenient())
+	if err != nil || !reflect.DeepEqual(got, []float64{1.5}) {
+		t.Errorf("expected [1.5], got %v %v", got, err)
+	}
+}
+
+func TestToStrings(t *testing.T) {
+	type name string
+	n := "p"
+	got, err := ToStrings([]interface{}{"a", name("b"), &n})
+	if err != nil || !reflect.DeepEqual(got, []string{"a", "b", "p"}) {
+		t.Errorf("expected [a b p], got %v %v", got, err)
+	}
+	if _, err := ToStrings([]interface{}{"a", 1}); err == nil {
+		t.Error("expected an int to need Lenient")
+	}
+	got, err = ToStrings([]interface{}{"a", 1, uint8(2), 1.5, true}, Lenient())
+	if err != nil || !reflect.DeepEqual(got, []string{"a", "1", "2", "1.5", "true"}) {
+		t.Errorf("expected [a 1 2 1.5 true], got %v %v", got, err)
+	}
+}
+
+func TestToBools(t *testing.T) {
+	got, err := ToBools([]bool{true, false})
+	if err != nil || !reflect.DeepEqual(got, []bool{true, false}) {
+		t.Errorf("expected [true false], got %v %v", got, err)
+	}
+	if _, err := ToBools("true"); err == nil {
+		t.Error("expected a string to need Lenient")
+	}
+	got, err = ToBools([]interface{}{"true", "0", 1, 0.0}, Lenient())
+	if err != nil || !reflect.DeepEqual(got, []bool{true, false, true, false}) {
+		t.Errorf("expected [true false true false], got %v %v", got, err)
+	}
+	if _, err := ToBools(2, Lenient()); err == nil {
+		t.Error("expected 2 to fail")
+	}
+}
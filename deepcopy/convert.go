@@ -0,0 +1,385 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ConvertOption configures how values are converted by Convert.
+type ConvertOption func(*converter)
+
+// StrictFields makes it an error for a struct field, in either the source or
+// the destination, to have no match. By default such fields are ignored.
+func StrictFields() ConvertOption {
+	return func(c *converter) {
+		c.strict = true
+	}
+}
+
+// ConvertCopyOptions sets the options values are deep copied with, as they
+// are by IfaceE.
+func ConvertCopyOptions(opts ...Option) ConvertOption {
+	return func(c *converter) {
+		c.copyOpts = append(c.copyOpts, opts...)
+	}
+}
+
+// Convert deep copies src into the value dst points to, converting between
+// compatible types. Whatever dst held before is replaced. Values of the same
+// type are deep copied the way IfaceE copies them; otherwise:
+//
+//   - struct fields are matched by name, or by the name in their deepcopy
+//     tag, e.g. `deepcopy:"name=UserID"`, and converted;
+//   - slices, arrays of the same length, maps, and pointers are converted
+//     element by element;
+//   - pointers and the values they point to are converted to each other, a
+//     nil pointer becoming the zero value;
+//   - numbers are converted when no value is lost, e.g. int32 to int64 or
+//     uint8 to float32, as are values of the same non-numeric kind, e.g. a
+//     string to a named string type;
+//   - values are set in interfaces they implement.
+//
+// Anything else results in a *ConvertError. Fields tagged `deepcopy:"-"` are
+// ignored and the deepcopy tags of the fields in dst are honored. Embedded
+// structs are matched like any other field.
+func Convert(dst, src interface{}, opts ...ConvertOption) error {
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return fmt.Errorf("deepcopy: cannot convert into %T, expected a non-nil pointer", dst)
+	}
+	d = d.Elem()
+	d.Set(reflect.Zero(d.Type()))
+	s := reflect.ValueOf(src)
+	if !s.IsValid() {
+		return nil
+	}
+	c := &converter{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.copier = newCopier(d.Type(), newConfig(c.copyOpts))
+	if c.unexported {
+		s = addressable(s)
+	}
+	return c.convert(d, s)
+}
+
+// ConvertError is returned by Convert when a value cannot be converted.
+type ConvertError struct {
+	Type reflect.Type // the type being converted to
+	Path Path         // the location of the value within Type
+	From reflect.Type // the type of the value that cannot be converted
+	To   reflect.Type // the type it cannot be converted to
+}
+
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("deepcopy: cannot convert %s to %s at %s", e.From, e.To, e.Path.in(e.Type))
+}
+
+// UnmappedError is returned by Convert, with StrictFields, when a struct
+// field has no match.
+type UnmappedError struct {
+	Type   reflect.Type // the type being converted to
+	Path   Path         // the location of the struct within Type
+	Struct reflect.Type // the struct type that has the field
+	Field  string       // the name of the field
+}
+
+func (e *UnmappedError) Error() string {
+	return fmt.Sprintf("deepcopy: field %s of %s has no match at %s", e.Field, e.Struct, e.Path.in(e.Type))
+}
+
+// converter holds the state of a single Convert. The copier copies the
+// values whose types match; its path is the location being converted.
+type converter struct {
+	*copier
+	strict   bool
+	copyOpts []Option
+}
+
+// convert converts src into dst, which must be settable and is the zero
+// value.
+func (c *converter) convert(dst, src reflect.Value) error {
+	if src.Type() == dst.Type() {
+		return c.copyRecursive(src, dst)
+	}
+	if src.Kind() == reflect.Interface {
+		if src.IsNil() {
+			return nil
+		}
+		elem := src.Elem()
+		if c.unexported {
+			elem = addressable(elem)
+		}
+		return c.convert(dst, elem)
+	}
+	switch {
+	case dst.Kind() == reflect.Interface:
+		if !src.Type().Implements(dst.Type()) {
+			break
+		}
+		cpy := reflect.New(src.Type()).Elem()
+		err := c.copyRecursive(src, cpy)
+		if err != nil {
+			return err
+		}
+		dst.Set(cpy)
+		return nil
+	case dst.Kind() == reflect.Ptr:
+		if src.Kind() != reflect.Ptr {
+			ptr := reflect.New(dst.Type().Elem())
+			dst.Set(ptr)
+			return c.convert(ptr.Elem(), src)
+		}
+		if src.IsNil() {
+			return nil
+		}
+		// A pointer that has already been converted to this type points at
+		// that conversion.
+		key := visit{ptr: src.Pointer(), typ: dst.Type()}
+		if v, ok := c.lookup(key); ok {
+			dst.Set(v)
+			return nil
+		}
+		ptr := reflect.New(dst.Type().Elem())
+		if v, ok := c.remember(key, ptr); ok {
+			dst.Set(v)
+			return nil
+		}
+		dst.Set(ptr)
+		return c.convert(ptr.Elem(), src.Elem())
+	case src.Kind() == reflect.Ptr:
+		if src.IsNil() {
+			return nil
+		}
+		return c.convert(dst, src.Elem())
+	case dst.Kind() == reflect.Struct && src.Kind() == reflect.Struct:
+		return c.convertStruct(dst, src)
+	case dst.Kind() == reflect.Slice && src.Kind() == reflect.Slice:
+		if c.copyNil(src) {
+			return nil
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		return c.convertElems(dst, src)
+	case dst.Kind() == reflect.Array && src.Kind() == reflect.Array && dst.Len() == src.Len():
+		return c.convertElems(dst, src)
+	case dst.Kind() == reflect.Map && src.Kind() == reflect.Map:
+		if c.copyNil(src) {
+			return nil
+		}
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), src.Len()))
+		return c.convertMap(dst, src)
+	case widens(src.Type(), dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return &ConvertError{Type: c.typ, Path: c.path.clone(), From: src.Type(), To: dst.Type()}
+}
+
+// convertElems converts the elements of the slice or array src into dst,
+// which has as many.
+func (c *converter) convertElems(dst, src reflect.Value) error {
+	for i := 0; i < src.Len(); i++ {
+		c.path = append(c.path, Step{Kind: IndexStep, Index: i})
+		err := c.convert(dst.Index(i), src.Index(i))
+		if err != nil {
+			return err
+		}
+		c.path = c.path[:len(c.path)-1]
+	}
+	return nil
+}
+
+// convertMap converts the entries of the map src into dst.
+func (c *converter) convertMap(dst, src reflect.Value) error {
+	for iter := src.MapRange(); iter.Next(); {
+		srcKey, srcValue := iter.Key(), iter.Value()
+		if c.unexported {
+			srcKey, srcValue = addressable(srcKey), addressable(srcValue)
+		}
+		c.path = append(c.path, Step{Kind: KeyStep, Key: srcKey})
+		key := reflect.New(dst.Type().Key()).Elem()
+		err := c.convert(key, srcKey)
+		if err != nil {
+			return err
+		}
+		value := reflect.New(dst.Type().Elem()).Elem()
+		err = c.convert(value, srcValue)
+		if err != nil {
+			return err
+		}
+		c.path = c.path[:len(c.path)-1]
+		dst.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// convertStruct converts the fields of src into the fields of dst they
+// match.
+func (c *converter) convertStruct(dst, src reflect.Value) error {
+	conv := conversionFor(dst.Type(), src.Type(), c.unexported)
+	if c.strict && len(conv.unmatched) > 0 {
+		u := conv.unmatched[0]
+		return &UnmappedError{Type: c.typ, Path: c.path.clone(), Struct: u.typ, Field: u.name}
+	}
+	for _, f := range conv.redacted {
+		field := dst.Field(f.index)
+		if !f.exported {
+			field = unlock(field)
+		}
+		c.redact(field)
+	}
+	for _, m := range conv.fields {
+		dstField, srcField := dst.Field(m.dst.index), src.Field(m.src.index)
+		if !m.dst.exported {
+			dstField = unlock(dstField)
+		}
+		if !m.src.exported {
+			srcField = unlock(srcField)
+		}
+		if m.dst.tag.mode == shallowField && srcField.Type().AssignableTo(dstField.Type()) {
+			dstField.Set(srcField)
+			continue
+		}
+		c.path = append(c.path, m.dst.step())
+		err := c.convert(dstField, srcField)
+		if err != nil {
+			return err
+		}
+		c.path = c.path[:len(c.path)-1]
+	}
+	return nil
+}
+
+// conversion is how the fields of one struct type are converted to those of
+// another.
+type conversion struct {
+	fields    []fieldMatch     // the fields that match
+	redacted  []fieldPlan      // the fields of dst that are redacted
+	unmatched []unmatchedField // the fields without a match
+}
+
+// fieldMatch is a field of dst and the field of src that is converted to it.
+type fieldMatch struct {
+	dst, src fieldPlan
+}
+
+// unmatchedField is a field that has no match.
+type unmatchedField struct {
+	typ  reflect.Type // the struct type that has the field
+	name string
+}
+
+// conversionKey identifies a cached conversion.
+type conversionKey struct {
+	dst, src   reflect.Type
+	unexported bool
+}
+
+// conversions is the cache of conversions, keyed by conversionKey.
+var conversions sync.Map
+
+// conversionFor returns the conversion of the struct type src to dst,
+// working it out if it isn't cached. Unexported fields are only matched if
+// unexported is true.
+func conversionFor(dst, src reflect.Type, unexported bool) *conversion {
+	key := conversionKey{dst, src, unexported}
+	if conv, ok := conversions.Load(key); ok {
+		return conv.(*conversion)
+	}
+	conv := &conversion{}
+	srcFields := make(map[string]fieldPlan)
+	var srcNames []string
+	for _, f := range planFor(src).fields {
+		if f.tag.mode == skipField || (!f.exported && !unexported) {
+			continue
+		}
+		srcFields[matchName(f)] = f
+		srcNames = append(srcNames, matchName(f))
+	}
+	matched := make(map[string]bool)
+	for _, f := range planFor(dst).fields {
+		if f.tag.mode == skipField || (!f.exported && !unexported) {
+			continue
+		}
+		if f.tag.mode == redactField {
+			conv.redacted = append(conv.redacted, f)
+			matched[matchName(f)] = true
+			continue
+		}
+		sf, ok := srcFields[matchName(f)]
+		if !ok {
+			conv.unmatched = append(conv.unmatched, unmatchedField{dst, f.name})
+			continue
+		}
+		matched[matchName(f)] = true
+		conv.fields = append(conv.fields, fieldMatch{dst: f, src: sf})
+	}
+	for _, name := range srcNames {
+		if !matched[name] {
+			conv.unmatched = append(conv.unmatched, unmatchedField{src, srcFields[name].name})
+		}
+	}
+	c, _ := conversions.LoadOrStore(key, conv)
+	return c.(*conversion)
+}
+
+// matchName returns the name a field is matched by: the name in its tag, if
+// it has one, or its own.
+func matchName(f fieldPlan) string {
+	if f.tag.name != "" {
+		return f.tag.name
+	}
+	return f.name
+}
+
+// widens reports whether every value of the type from can be converted to
+// the type to without loss: numbers that fit and values of the same
+// non-numeric kind.
+func widens(from, to reflect.Type) bool {
+	switch {
+	case isInt(from) && isInt(to), isUint(from) && isUint(to), isFloat(from) && isFloat(to), isComplex(from) && isComplex(to):
+		return to.Bits() >= from.Bits()
+	case isUint(from) && isInt(to):
+		return to.Bits() > from.Bits()
+	case (isInt(from) || isUint(from)) && isFloat(to):
+		// The integer has to fit in the float's mantissa.
+		mantissa := 24
+		if to.Bits() == 64 {
+			mantissa = 53
+		}
+		return from.Bits() <= mantissa
+	case isFloat(from) && isComplex(to):
+		return to.Bits()/2 >= from.Bits()
+	}
+	switch from.Kind() {
+	case reflect.Bool, reflect.String:
+		return from.Kind() == to.Kind()
+	}
+	return false
+}
+
+func isInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isFloat(t reflect.Type) bool {
+	return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+func isComplex(t reflect.Type) bool {
+	return t.Kind() == reflect.Complex64 || t.Kind() == reflect.Complex128
+}
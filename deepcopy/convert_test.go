@@ -0,0 +1,183 @@
+package deepcopy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type userDTO struct {
+	UserID   int32
+	Name     string
+	Email    *string
+	Tags     []string
+	Address  *addressDTO
+	Scores   map[string]int16
+	Created  time.Time
+	Password string
+	Internal string `deepcopy:"-"`
+}
+
+type addressDTO struct {
+	Street string
+	Zip    uint16
+}
+
+type role string
+
+type user struct {
+	ID       int64 `deepcopy:"name=UserID"`
+	Name     role
+	Email    string
+	Tags     []string
+	Address  address
+	Scores   map[string]float64
+	Created  time.Time
+	Password string `deepcopy:"redact"`
+	Note     string `deepcopy:"-"`
+}
+
+type address struct {
+	Street string
+	Zip    int
+}
+
+func TestConvert(t *testing.T) {
+	email := "a@example.com"
+	now := time.Now()
+	dto := userDTO{
+		UserID:   7,
+		Name:     "admin",
+		Email:    &email,
+		Tags:     []string{"a", "b"},
+		Address:  &addressDTO{Street: "Main", Zip: 12345},
+		Scores:   map[string]int16{"go": 9},
+		Created:  now,
+		Password: "hunter2",
+		Internal: "x",
+	}
+	u := user{Note: "replaced"}
+	err := Convert(&u, dto, StrictFields())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := user{
+		ID:      7,
+		Name:    "admin",
+		Email:   "a@example.com",
+		Tags:    []string{"a", "b"},
+		Address: address{Street: "Main", Zip: 12345},
+		Scores:  map[string]float64{"go": 9},
+		Created: now,
+	}
+	if !reflect.DeepEqual(u, expected) {
+		t.Errorf("expected %+v, got %+v", expected, u)
+	}
+	if shared := Shares(dto, u); len(shared) != 0 {
+		t.Errorf("expected the result to share nothing with src, got %v", shared)
+	}
+
+	// Back again: values become pointers and fields match by the tag.
+	var back userDTO
+	err = Convert(&back, &u)
+	if err == nil {
+		t.Fatal("expected int64 to int32 to fail")
+	}
+	var cerr *ConvertError
+	if !errors.As(err, &cerr) || cerr.Path.String() != "UserID" {
+		t.Errorf("expected a ConvertError at UserID, got %v", err)
+	}
+}
+
+func TestConvertUnmapped(t *testing.T) {
+	type a struct {
+		Name  string
+		Extra int
+	}
+	type b struct {
+		Name    string
+		Missing int
+	}
+	var dst b
+	if err := Convert(&dst, a{Name: "x"}); err != nil || dst.Name != "x" {
+		t.Errorf("expected unmatched fields to be ignored, got %v %+v", err, dst)
+	}
+	err := Convert(&dst, a{Name: "x"}, StrictFields())
+	var uerr *UnmappedError
+	if !errors.As(err, &uerr) || uerr.Field != "Missing" {
+		t.Errorf("expected an UnmappedError for Missing, got %v", err)
+	}
+	type c struct {
+		Name  string
+		Extra int
+		More  bool
+	}
+	err = Convert(&dst, []c{{}}, StrictFields())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	err = Convert(&[]a{}, []c{{}}, StrictFields())
+	if !errors.As(err, &uerr) || uerr.Field != "More" || uerr.Path.String() != "[0]" {
+		t.Errorf("expected an UnmappedError for More at [0], got %v", err)
+	}
+}
+
+func TestWidens(t *testing.T) {
+	type myString string
+	tests := []struct {
+		from, to interface{}
+		expected bool
+	}{
+		{int8(0), int64(0), true},
+		{int64(0), int32(0), false},
+		{uint8(0), int16(0), true},
+		{uint16(0), int16(0), false},
+		{int16(0), uint64(0), false},
+		{uint16(0), float32(0), true},
+		{int32(0), float32(0), false},
+		{int32(0), float64(0), true},
+		{int64(0), float64(0), false},
+		{float32(0), float64(0), true},
+		{float64(0), float32(0), false},
+		{float32(0), complex64(0), true},
+		{float64(0), complex64(0), false},
+		{"", myString(""), true},
+		{true, "", false},
+		{0, "", false},
+	}
+	for _, test := range tests {
+		from, to := reflect.TypeOf(test.from), reflect.TypeOf(test.to)
+		if got := widens(from, to); got != test.expected {
+			t.Errorf("%s to %s: expected %t, got %t", from, to, test.expected, got)
+		}
+	}
+}
+
+func TestConvertInterfaces(t *testing.T) {
+	type src struct {
+		Value  time.Duration
+		Any    interface{}
+		Ptrs   []*int
+		Shared *int
+	}
+	type dst struct {
+		Value  fmt.Stringer
+		Any    int64
+		Ptrs   []int
+		Shared *int64
+	}
+	n := 3
+	var d dst
+	err := Convert(&d, src{Value: time.Second, Any: int32(5), Ptrs: []*int{&n, nil}, Shared: &n})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.Value != time.Second || d.Any != 5 || !reflect.DeepEqual(d.Ptrs, []int{3, 0}) || *d.Shared != 3 {
+		t.Errorf("unexpected result %+v", d)
+	}
+	if err := Convert(d, src{}); err == nil {
+		t.Error("expected an error for a non-pointer dst")
+	}
+}
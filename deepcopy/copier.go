@@ -0,0 +1,122 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DeepCopier is implemented by types that know how to copy themselves
+// better than reflection does, e.g. pooled buffers or handles that need to
+// be reopened. DeepCopy must return a value of the same type as its
+// receiver.
+type DeepCopier interface {
+	DeepCopy() interface{}
+}
+
+// CopierFunc returns a deep copy of src. The returned value must be
+// assignable to src's type; an invalid reflect.Value results in the zero
+// value.
+type CopierFunc func(src reflect.Value) (reflect.Value, error)
+
+var deepCopierType = reflect.TypeOf((*DeepCopier)(nil)).Elem()
+
+// copiers holds the registered copiers by type.
+var copiers = struct {
+	sync.RWMutex
+	m map[reflect.Type]CopierFunc
+}{m: make(map[reflect.Type]CopierFunc)}
+
+// RegisterCopier registers fn as the copier for values of type t. This is
+// meant for types that can't implement DeepCopier, e.g. those from other
+// packages. A registered copier takes precedence over a DeepCopy method.
+// Registering a nil fn removes the copier for t.
+//
+// Copiers should be registered before copying starts, e.g. in an init func:
+// copies that are in progress may or may not use the new copier.
+func RegisterCopier(t reflect.Type, fn func(src reflect.Value) (reflect.Value, error)) {
+	copiers.Lock()
+	defer copiers.Unlock()
+	// Plans include the copier of their type, so they all have to be
+	// compiled again.
+	plans.Clear()
+	if fn == nil {
+		delete(copiers.m, t)
+		return
+	}
+	copiers.m[t] = fn
+}
+
+// lookupCopier returns the copier registered for t, if any.
+func lookupCopier(t reflect.Type) CopierFunc {
+	copiers.RLock()
+	defer copiers.RUnlock()
+	return copiers.m[t]
+}
+
+// callDeepCopy is the CopierFunc for types that implement DeepCopier.
+func callDeepCopy(src reflect.Value) (reflect.Value, error) {
+	return reflect.ValueOf(src.Interface().(DeepCopier).DeepCopy()), nil
+}
+
+// callElemDeepCopy is the CopierFunc for pointers to types that implement
+// DeepCopier with a value receiver: the copy points to a new value holding
+// the element's copy.
+func callElemDeepCopy(src reflect.Value) (reflect.Value, error) {
+	v := reflect.ValueOf(src.Elem().Interface().(DeepCopier).DeepCopy())
+	if !v.IsValid() || !v.Type().AssignableTo(src.Type().Elem()) {
+		return v, nil
+	}
+	cpy := reflect.New(src.Type().Elem())
+	cpy.Elem().Set(v)
+	return cpy, nil
+}
+
+// CopierError is returned when a registered copier or a DeepCopy method
+// fails or returns a value of the wrong type.
+type CopierError struct {
+	Type reflect.Type // the type of the value being copied
+	Path Path         // the location of the value within Type
+	Err  error
+}
+
+func (e *CopierError) Error() string {
+	return fmt.Sprintf("deepcopy: cannot copy %s: %s", e.Path.in(e.Type), e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CopierError) Unwrap() error {
+	return e.Err
+}
+
+// copyCustom copies original with fn, its registered copier or DeepCopy
+// method. Pointers are recorded as visited so that aliasing is preserved for
+// them too.
+func (c *copier) copyCustom(fn CopierFunc, original, cpy reflect.Value) error {
+	var key visit
+	if original.Kind() == reflect.Ptr {
+		if original.IsNil() {
+			return nil
+		}
+		key = visit{ptr: original.Pointer(), typ: original.Type()}
+		if v, ok := c.lookup(key); ok {
+			cpy.Set(v)
+			return nil
+		}
+	}
+	v, err := fn(original)
+	if err != nil {
+		return &CopierError{Type: c.typ, Path: c.path.clone(), Err: err}
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	if !v.Type().AssignableTo(cpy.Type()) {
+		return &CopierError{Type: c.typ, Path: c.path.clone(), Err: fmt.Errorf("copier returned %s, expected %s", v.Type(), cpy.Type())}
+	}
+	if original.Kind() == reflect.Ptr {
+		v, _ = c.remember(key, v)
+	}
+	cpy.Set(v)
+	return nil
+}
@@ -0,0 +1,124 @@
+package deepcopy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// buffer is a DeepCopier that resets its cache when copied.
+type buffer struct {
+	Data  []byte
+	Cache map[string]int
+}
+
+func (b *buffer) DeepCopy() interface{} {
+	return &buffer{Data: append([]byte(nil), b.Data...)}
+}
+
+// handle is a type that is only copied by a registered copier.
+type handle struct {
+	Name   string
+	Opened bool
+}
+
+func TestDeepCopier(t *testing.T) {
+	type holder struct {
+		A *buffer
+		B *buffer
+	}
+	b := &buffer{Data: []byte("hello"), Cache: map[string]int{"a": 1}}
+	cpy := Iface(holder{A: b, B: b}).(holder)
+	if cpy.A == b {
+		t.Fatal("expected the buffer to be copied, got the original")
+	}
+	if string(cpy.A.Data) != "hello" {
+		t.Errorf("expected data to be \"hello\", got %q", cpy.A.Data)
+	}
+	if cpy.A.Cache != nil {
+		t.Errorf("expected the cache to be reset by DeepCopy, got %v", cpy.A.Cache)
+	}
+	if cpy.A != cpy.B {
+		t.Error("expected A and B to point at the same copied buffer")
+	}
+}
+
+// version is a DeepCopier with a value receiver that bumps its number when
+// copied.
+type version struct {
+	N    int
+	Tags []string
+}
+
+func (v version) DeepCopy() interface{} {
+	return version{N: v.N + 1, Tags: append([]string(nil), v.Tags...)}
+}
+
+func TestDeepCopierValueReceiver(t *testing.T) {
+	type holder struct {
+		V version
+		P *version
+		Q *version
+	}
+	p := &version{N: 1, Tags: []string{"a"}}
+	cpy, err := IfaceE(holder{V: version{N: 5}, P: p, Q: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := cpy.(holder)
+	if h.V.N != 6 {
+		t.Errorf("expected V to be copied by DeepCopy, got %d", h.V.N)
+	}
+	if h.P == p {
+		t.Fatal("expected P to be copied, got the original")
+	}
+	if h.P.N != 2 || !reflect.DeepEqual(h.P.Tags, p.Tags) {
+		t.Errorf("expected P to be copied by DeepCopy, got %+v", *h.P)
+	}
+	if h.P != h.Q {
+		t.Error("expected P and Q to point at the same copy")
+	}
+	if cpy := Iface(holder{}).(holder); cpy.P != nil {
+		t.Errorf("expected a nil pointer to stay nil, got %+v", cpy.P)
+	}
+}
+
+func TestRegisterCopier(t *testing.T) {
+	typ := reflect.TypeOf(handle{})
+	RegisterCopier(typ, func(src reflect.Value) (reflect.Value, error) {
+		h := src.Interface().(handle)
+		return reflect.ValueOf(handle{Name: h.Name, Opened: true}), nil
+	})
+	defer RegisterCopier(typ, nil)
+
+	cpy := Iface([]handle{{Name: "a"}}).([]handle)
+	if !cpy[0].Opened || cpy[0].Name != "a" {
+		t.Errorf("expected the registered copier to be used, got %#v", cpy[0])
+	}
+
+	errClosed := errors.New("handle closed")
+	RegisterCopier(typ, func(src reflect.Value) (reflect.Value, error) {
+		return reflect.Value{}, errClosed
+	})
+	_, err := IfaceE(map[string]handle{"h": {Name: "h"}})
+	if !errors.Is(err, errClosed) {
+		t.Fatalf("expected %q, got %v", errClosed, err)
+	}
+	if err.Error() != `deepcopy: cannot copy map[string]deepcopy.handle["h"]: handle closed` {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	RegisterCopier(typ, func(src reflect.Value) (reflect.Value, error) {
+		return reflect.ValueOf("handle"), nil
+	})
+	_, err = IfaceE(handle{})
+	if _, ok := err.(*CopierError); !ok {
+		t.Errorf("expected a *CopierError, got %v", err)
+	}
+
+	RegisterCopier(typ, nil)
+	cpy = Iface([]handle{{Name: "a"}}).([]handle)
+	if cpy[0].Opened {
+		t.Error("expected the copier to be removed")
+	}
+}
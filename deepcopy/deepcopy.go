@@ -9,72 +9,381 @@
 package deepcopy
 
 import (
+	"fmt"
 	"reflect"
+	"unsafe"
 )
 
 // InterfaceToSliceOfStrings takes an interface that is either a slice of
 // strings or a string and returns a deep copy of it as a slice of strings.
+// Nil is returned if it is anything else; use ToStrings to find out why.
 func InterfaceToSliceOfStrings(v interface{}) []string {
-	if v == nil {
-		return nil
-	}
-	var sl []string
-	switch reflect.TypeOf(v).Kind() {
-	case reflect.Slice:
-		s := reflect.ValueOf(v)
-		sl = make([]string, s.Len(), s.Len())
-		for i := 0; i < s.Len(); i++ {
-			sl[i] = s.Index(i).Interface().(string)
-		}
-	case reflect.String:
-		sl = append(sl, reflect.ValueOf(v).Interface().(string))
-	default:
-		return nil
-	}
+	sl, _ := ToStrings(v)
 	return sl
 }
 
-// InterfaceToSliceOfInts takes an interface that is a slice of ints and returns
-// a deep copy of it as a slice of strings. An error is returned if the
-// interface is not a slice of strings.
+// InterfaceToSliceOfInts takes an interface that is either a slice of ints
+// or an int and returns a deep copy of it as a slice of ints. Nil is
+// returned if it is anything else; use ToInts to find out why.
 func InterfaceToSliceOfInts(v interface{}) []int {
-	if v == nil {
-		return nil
-	}
-	var sl []int
-	switch reflect.TypeOf(v).Kind() {
-	case reflect.Slice:
-		s := reflect.ValueOf(v)
-		sl = make([]int, s.Len(), s.Len())
-		for i := 0; i < s.Len(); i++ {
-			sl[i] = s.Index(i).Interface().(int)
-		}
-	case reflect.Int:
-		sl = append(sl, reflect.ValueOf(v).Interface().(int))
-	default:
-		return nil
-	}
+	sl, _ := ToInts(v)
 	return sl
 }
 
-// Iface recursively deep copies an interface{}
+// Iface recursively deep copies an interface{}. Values that cannot be deep
+// copied, e.g. channels and funcs, are shared with the original. Callers
+// that know the type of the value should use Copy instead.
 func Iface(iface interface{}) interface{} {
+	cpy, _ := IfaceE(iface, OnUncopyable(Share))
+	return cpy
+}
+
+// Copy returns a deep copy of v. It copies the same way as Iface does, but
+// the copy doesn't need to be type asserted.
+func Copy[T any](v T, opts ...Option) T {
+	var cpy T
+	CopyInto(&cpy, v, opts...)
+	return cpy
+}
+
+// CopyInto deep copies src into the value dst points to. Whatever dst held
+// before is replaced. Errors from registered copiers and DeepCopy methods are
+// ignored; use IfaceE to see them.
+//
+// A copy stopped part way would be silently incomplete, so CopyInto, and
+// Copy, panic when given options that stop a copy with an error: the limits
+// and OnUncopyable(Strict). Use IfaceE with those.
+func CopyInto[T any](dst *T, src T, opts ...Option) {
+	cfg := newConfig(append([]Option{OnUncopyable(Share)}, opts...))
+	if cfg.limited() || cfg.uncopyable == Strict {
+		panic("deepcopy: Copy and CopyInto cannot report errors; use IfaceE with limits and OnUncopyable(Strict)")
+	}
+	var zero T
+	*dst = zero
+	original := reflect.ValueOf(&src).Elem()
+	c := newCopier(original.Type(), cfg)
+	c.copyRecursive(original, reflect.ValueOf(dst).Elem())
+}
+
+// IfaceE recursively deep copies an interface{}. Unlike Iface, it returns an
+// error when a value cannot be copied; by default, that is whenever a
+// channel, func, or unsafe.Pointer is found. Use OnUncopyable to skip or
+// share those values instead, or OnChan and OnFunc to choose for channels
+// and funcs alone.
+//
+// Unexported struct fields are left as their zero value unless
+// CopyUnexported is used. How a struct field is copied can be changed with
+// its deepcopy tag:
+//
+//	Cache  map[string]int `deepcopy:"-"`       // left as the zero value
+//	Logger *log.Logger    `deepcopy:"shallow"` // shares the original value
+//	Secret string         `deepcopy:"redact"`  // zero value or placeholder
+//
+// Redacted strings are set to the placeholder set by RedactPlaceholder.
+//
+// Nil slices and maps are copied as nil and empty ones as empty, unless
+// OnNil says otherwise. Map keys are used as they are, unless CopyMapKeys
+// is used. Standard library types whose state is unexported, e.g. time.Time
+// and big.Int, are always copied correctly; sync primitives, e.g. sync.Mutex
+// and sync.WaitGroup, are reset to their zero value rather than copied in
+// whatever state they are in.
+//
+// Values can be changed while they are copied with WithHook, e.g. to mask
+// secrets, and what a copy does can be recorded with WithStats.
+//
+// Copies of untrusted values can be bounded with MaxDepth, MaxNodes,
+// MaxLength, and WithContext; a copy that exceeds them stops with a
+// *LimitError.
+func IfaceE(iface interface{}, opts ...Option) (interface{}, error) {
 	if iface == nil {
-		return nil
+		return nil, nil
 	}
+	cfg := newConfig(opts)
 	// Make the interface a reflect.Value
 	original := reflect.ValueOf(iface)
+	// Unexported fields can only be read through an addressable value.
+	if cfg.unexported {
+		original = addressable(original)
+	}
 	// Make a copy of the same type as the original.
 	cpy := reflect.New(original.Type()).Elem()
 	// Recursively copy the original.
-	copyRecursive(original, cpy)
-	// Return theb copy as an interface.
-	return cpy.Interface()
+	c := newCopier(original.Type(), cfg)
+	err := c.copyRecursive(original, cpy)
+	if err != nil {
+		return nil, err
+	}
+	// Return the copy as an interface.
+	return cpy.Interface(), nil
+}
+
+// UncopyableError is returned when a value that cannot be deep copied is
+// found while the Strict policy is in effect.
+type UncopyableError struct {
+	Type reflect.Type // the type of the value being copied
+	Path Path         // the location of the value within Type
+	Kind reflect.Kind // the kind of the value that cannot be copied
+}
+
+func (e *UncopyableError) Error() string {
+	return fmt.Sprintf("deepcopy: cannot copy %s at %s", e.Kind, e.Path.in(e.Type))
+}
+
+// visit identifies something that has already been copied. The type is part
+// of the key because a pointer to a struct and a pointer to its first field
+// share the same address, and the length is because slices that start at
+// the same place but have different lengths are different values.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int // for slices
+}
+
+// copier holds the state of a single deep copy. Everything that is reached
+// through a pointer or is a map or slice is recorded in visited so that
+// cycles terminate and values that are shared in the original are also
+// shared in the copy.
+type copier struct {
+	config
+	typ      reflect.Type            // the type of the value being copied
+	path     Path                    // the location of the value currently being copied
+	visits   []visited               // what has been copied, until there's a lot of it
+	visited  map[visit]reflect.Value // what has been copied, once there's a lot of it
+	visitBuf [4]visited              // the initial backing array of visits
+	pathBuf  [4]Step                 // the initial backing array of path
+	limited  bool                    // whether the config sets any limits
+	counts   *counters               // what has been copied, when limited
+	par      *parallel               // the state shared with the workers, once copying in parallel
+	traced   tally                   // what has been copied, when recording stats
+	tracing  int                     // the depth of the value being copied, when recording stats
+}
+
+// visited is a visit and the copy that was made for it.
+type visited struct {
+	visit
+	cpy reflect.Value
+}
+
+// maxVisits is how many visits are kept in a slice before they are moved to
+// a map. Most values have few pointers or maps and searching a short slice
+// is cheaper than making a map.
+const maxVisits = 16
+
+func newCopier(typ reflect.Type, cfg config) *copier {
+	c := &copier{config: cfg, typ: typ, limited: cfg.limited()}
+	c.visits = c.visitBuf[:0]
+	c.path = c.pathBuf[:0]
+	if c.limited {
+		c.counts = new(counters)
+	}
+	return c
+}
+
+// lookup returns the copy of what key identifies, if it has been copied.
+func (c *copier) lookup(key visit) (reflect.Value, bool) {
+	if c.par != nil {
+		return c.par.lookup(key)
+	}
+	if c.visited != nil {
+		v, ok := c.visited[key]
+		return v, ok
+	}
+	for _, v := range c.visits {
+		if v.visit == key {
+			return v.cpy, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// remember records v as the copy of what key identifies. When copying in
+// parallel, another worker may have recorded a copy since lookup was called;
+// that copy is returned instead, and v is to be discarded.
+func (c *copier) remember(key visit, v reflect.Value) (reflect.Value, bool) {
+	if c.par != nil {
+		return c.par.remember(key, v)
+	}
+	if c.visited == nil && len(c.visits) < maxVisits {
+		c.visits = append(c.visits, visited{key, v})
+		return v, false
+	}
+	if c.visited == nil {
+		c.visited = make(map[visit]reflect.Value, 2*maxVisits)
+		for _, v := range c.visits {
+			c.visited[v.visit] = v.cpy
+		}
+		c.visits = nil
+	}
+	c.visited[key] = v
+	return v, false
+}
+
+// redact sets a redacted field in the copy: strings get the placeholder,
+// everything else is left as its zero value.
+func (c *copier) redact(cpy reflect.Value) {
+	if cpy.Kind() == reflect.String {
+		cpy.SetString(c.placeholder)
+	}
+}
+
+// copyKey returns a deep copy of key, a map key that is copied with p.
+func (c *copier) copyKey(p *plan, key reflect.Value) (reflect.Value, error) {
+	if c.unexported {
+		key = addressable(key)
+	}
+	cpy := reflect.New(key.Type()).Elem()
+	c.path = append(c.path, Step{Kind: KeyStep, Key: key})
+	err := c.copyPlanned(p, key, cpy)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	c.path = c.path[:len(c.path)-1]
+	return cpy, nil
+}
+
+// copyEntry returns deep copies of the key and value of a map entry. The key
+// is only copied if keyPlan isn't nil; the value is copied with elem.
+func (c *copier) copyEntry(elem, keyPlan *plan, key, value reflect.Value) (reflect.Value, reflect.Value, error) {
+	if keyPlan != nil {
+		var err error
+		key, err = c.copyKey(keyPlan, key)
+		if err != nil {
+			return key, value, err
+		}
+	}
+	// Values that contain nothing to deep copy are set as is.
+	if c.isFlat(elem) {
+		return key, value, nil
+	}
+	if c.unexported {
+		value = addressable(value)
+	}
+	cpy := reflect.New(value.Type()).Elem()
+	c.path = append(c.path, Step{Kind: KeyStep, Key: key})
+	err := c.copyPlanned(elem, value, cpy)
+	if err != nil {
+		return key, cpy, err
+	}
+	c.path = c.path[:len(c.path)-1]
+	return key, cpy, nil
+}
+
+// copyChan copies the channel original into cpy according to the channel
+// policy.
+func (c *copier) copyChan(original, cpy reflect.Value) error {
+	switch c.chans {
+	case ShareChans:
+		cpy.Set(original)
+	case NewChans:
+		// Channels are made, and remembered, in both directions, so that
+		// views of the same channel in one direction, e.g. <-chan T, get the
+		// same new channel.
+		t := reflect.ChanOf(reflect.BothDir, original.Type().Elem())
+		key := visit{ptr: original.Pointer(), typ: t}
+		ch, ok := c.lookup(key)
+		if !ok {
+			ch = reflect.MakeChan(t, original.Cap())
+			if v, ok := c.remember(key, ch); ok {
+				ch = v
+			} else {
+				c.alloc(reflect.Chan, original.Cap()*int(t.Elem().Size()))
+			}
+		}
+		cpy.Set(ch.Convert(original.Type()))
+	}
+	return nil
+}
+
+// copyNil reports whether the copy of original, a slice or a map, is nil
+// according to the nil policy. Otherwise, a new slice or map needs to be
+// made, even if original is nil.
+func (c *copier) copyNil(original reflect.Value) bool {
+	switch c.nils {
+	case NilToEmpty:
+		return false
+	case EmptyToNil:
+		return original.Len() == 0
+	}
+	return original.IsNil()
+}
+
+// addressable returns v if it is addressable. Otherwise, it returns an
+// addressable copy of v, so that the unexported fields of structs within it
+// can be unlocked.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Array:
+		a := reflect.New(v.Type()).Elem()
+		a.Set(v)
+		return a
+	}
+	return v
+}
+
+// unlock returns v without the restrictions reflect places on values that
+// were obtained through unexported struct fields, so they can be read and
+// set like any other value. v must be addressable.
+func unlock(v reflect.Value) reflect.Value {
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
 }
 
 // copyRecursive does the actual copying of the interface. It currently has
 // limited support for what it can handle. Add as needed.
-func copyRecursive(original, cpy reflect.Value) {
+func (c *copier) copyRecursive(original, cpy reflect.Value) error {
+	return c.copyPlanned(planFor(original.Type()), original, cpy)
+}
+
+// copyPlanned copies original using p, which must be the plan for its type.
+// Struct fields and slice elements are copied with the plans their parent's
+// plan holds, saving a lookup in the plan cache for each of them.
+func (c *copier) copyPlanned(p *plan, original, cpy reflect.Value) error {
+	if c.limited {
+		if err := c.checkLimits(); err != nil {
+			return err
+		}
+	}
+	if c.trace != nil {
+		return c.copyTraced(p, original, cpy)
+	}
+	return c.copyNode(p, original, cpy)
+}
+
+// copyTraced copies original like copyNode does, recording it in the stats.
+func (c *copier) copyTraced(p *plan, original, cpy reflect.Value) error {
+	depth, before, outer := len(c.path), c.traced, c.tracing
+	c.traced.nodes++
+	c.tracing = depth
+	err := c.copyNode(p, original, cpy)
+	c.tracing = outer
+	// Pointers and interfaces don't add to the path, so the values they
+	// hold are at the same path; only the outermost one is a subtree.
+	c.traceNode(depth, before, depth != outer)
+	return err
+}
+
+// copyNode copies original, once it has been counted for the limits and the
+// stats.
+func (c *copier) copyNode(p *plan, original, cpy reflect.Value) error {
+	// Hooks can change what is copied, or copy it themselves.
+	if len(c.hooks) > 0 {
+		v, done, err := c.runHooks(original, cpy)
+		if done || err != nil {
+			return err
+		}
+		original = v
+	}
+	// Values that contain nothing to deep copy are copied as a whole.
+	if c.isFlat(p) {
+		cpy.Set(original)
+		return nil
+	}
+	// Types that know how to copy themselves are left to do so.
+	if p.copier != nil {
+		return c.copyCustom(p.copier, original, cpy)
+	}
 	// handle according to original's Kind
 	switch original.Kind() {
 	case reflect.Ptr:
@@ -82,41 +391,201 @@ func copyRecursive(original, cpy reflect.Value) {
 		originalValue := original.Elem()
 		// if  it isn't valid, return.
 		if !originalValue.IsValid() {
-			return
+			return nil
+		}
+		// If this pointer has already been copied, point at that copy.
+		key := visit{ptr: original.Pointer(), typ: original.Type()}
+		if v, ok := c.lookup(key); ok {
+			cpy.Set(v)
+			return nil
+		}
+		ptr := reflect.New(originalValue.Type())
+		if v, ok := c.remember(key, ptr); ok {
+			cpy.Set(v)
+			return nil
 		}
-		cpy.Set(reflect.New(originalValue.Type()))
-		copyRecursive(originalValue, cpy.Elem())
+		c.alloc(reflect.Ptr, int(originalValue.Type().Size()))
+		cpy.Set(ptr)
+		return c.copyPlanned(p.elemPlan(original.Type()), originalValue, cpy.Elem())
 	case reflect.Interface:
 		// Get the value for the interface, not the pointer.
 		originalValue := original.Elem()
 		if !originalValue.IsValid() {
-			return
+			return nil
+		}
+		if c.unexported {
+			originalValue = addressable(originalValue)
 		}
 		// Get the value by calling Elem().
 		copyValue := reflect.New(originalValue.Type()).Elem()
-		copyRecursive(originalValue, copyValue)
+		c.alloc(reflect.Interface, int(originalValue.Type().Size()))
+		err := c.copyRecursive(originalValue, copyValue)
+		if err != nil {
+			return err
+		}
 		cpy.Set(copyValue)
 	case reflect.Struct:
 		// Go through each field of the struct and copy it.
-		for i := 0; i < original.NumField(); i++ {
-			if cpy.Field(i).CanSet() {
-				copyRecursive(original.Field(i), cpy.Field(i))
+		for _, f := range p.fields {
+			originalField, copyField := original.Field(f.index), cpy.Field(f.index)
+			if !f.exported {
+				if !c.unexported {
+					continue
+				}
+				originalField, copyField = unlock(originalField), unlock(copyField)
 			}
+			// The field's tag may say it isn't to be deep copied.
+			switch f.tag.mode {
+			case skipField:
+				continue
+			case shallowField:
+				copyField.Set(originalField)
+				continue
+			case redactField:
+				c.redact(copyField)
+				continue
+			}
+			c.path = append(c.path, f.step())
+			err := c.copyPlanned(f.plan, originalField, copyField)
+			if err != nil {
+				return err
+			}
+			c.path = c.path[:len(c.path)-1]
 		}
 	case reflect.Slice:
-		// Make a new slice and copy each element.
-		cpy.Set(reflect.MakeSlice(original.Type(), original.Len(), original.Cap()))
+		// Whether the copy is nil depends on the nil policy.
+		if c.copyNil(original) {
+			return nil
+		}
+		if c.limited {
+			if err := c.addLength(original.Len()); err != nil {
+				return err
+			}
+		}
+		// A slice that has already been copied is shared, not copied again.
+		// Empty slices, and those of zero size elements, can point at the
+		// same memory without being related, so they aren't recorded.
+		var seen visit
+		recorded := original.Len() > 0 && original.Type().Elem().Size() > 0
+		if recorded {
+			seen = visit{ptr: original.Pointer(), typ: original.Type(), len: original.Len()}
+			if v, ok := c.lookup(seen); ok {
+				cpy.Set(v)
+				return nil
+			}
+		}
+		s := reflect.MakeSlice(original.Type(), original.Len(), original.Cap())
+		if recorded {
+			if v, ok := c.remember(seen, s); ok {
+				cpy.Set(v)
+				return nil
+			}
+		}
+		// Copy each element into the new slice.
+		cpy.Set(s)
+		c.alloc(reflect.Slice, original.Cap()*int(original.Type().Elem().Size()))
+		// Elements that contain nothing to deep copy are copied in bulk.
+		elem := p.elemPlan(original.Type())
+		if c.isFlat(elem) {
+			reflect.Copy(cpy, original)
+			return nil
+		}
+		// Large slices have their elements copied in parallel, if enabled.
+		if c.workers > 1 && original.Len() >= c.threshold {
+			return c.copyParallel(original.Len(), func(w *copier, i int) error {
+				w.path = append(w.path, Step{Kind: IndexStep, Index: i})
+				err := w.copyPlanned(elem, original.Index(i), cpy.Index(i))
+				if err != nil {
+					return err
+				}
+				w.path = w.path[:len(w.path)-1]
+				return nil
+			})
+		}
+		for i := 0; i < original.Len(); i++ {
+			c.path = append(c.path, Step{Kind: IndexStep, Index: i})
+			err := c.copyPlanned(elem, original.Index(i), cpy.Index(i))
+			if err != nil {
+				return err
+			}
+			c.path = c.path[:len(c.path)-1]
+		}
+	case reflect.Array:
+		// Copy each element; flat arrays were copied as a whole above.
+		elem := p.elemPlan(original.Type())
 		for i := 0; i < original.Len(); i++ {
-			copyRecursive(original.Index(i), cpy.Index(i))
+			c.path = append(c.path, Step{Kind: IndexStep, Index: i})
+			err := c.copyPlanned(elem, original.Index(i), cpy.Index(i))
+			if err != nil {
+				return err
+			}
+			c.path = c.path[:len(c.path)-1]
 		}
 	case reflect.Map:
-		cpy.Set(reflect.MakeMap(original.Type()))
-		for _, key := range original.MapKeys() {
-			originalValue := original.MapIndex(key)
-			copyValue := reflect.New(originalValue.Type()).Elem()
-			copyRecursive(originalValue, copyValue)
+		// Whether the copy is nil depends on the nil policy.
+		if c.copyNil(original) {
+			return nil
+		}
+		if c.limited {
+			if err := c.addLength(original.Len()); err != nil {
+				return err
+			}
+		}
+		// A map that has already been copied is shared, not copied again.
+		m := reflect.MakeMap(original.Type())
+		if !original.IsNil() {
+			seen := visit{ptr: original.Pointer(), typ: original.Type()}
+			if v, ok := c.lookup(seen); ok {
+				cpy.Set(v)
+				return nil
+			}
+			if v, ok := c.remember(seen, m); ok {
+				cpy.Set(v)
+				return nil
+			}
+		}
+		cpy.Set(m)
+		c.alloc(reflect.Map, original.Len()*int(original.Type().Key().Size()+original.Type().Elem().Size()))
+		elem := p.elemPlan(original.Type())
+		// Keys are only copied when asked to and they aren't flat.
+		var keyPlan *plan
+		if c.mapKeys {
+			keyPlan = planFor(original.Type().Key())
+			if keyPlan.isFlat(c.unexported) {
+				keyPlan = nil
+			}
+		}
+		// Large maps have their entries copied in parallel, if enabled.
+		if c.workers > 1 && original.Len() >= c.threshold {
+			return c.copyMapParallel(elem, keyPlan, original, cpy)
+		}
+		for iter := original.MapRange(); iter.Next(); {
+			key, copyValue, err := c.copyEntry(elem, keyPlan, iter.Key(), iter.Value())
+			if err != nil {
+				return err
+			}
 			cpy.SetMapIndex(key, copyValue)
 		}
+	// These can't be deep copied; what happens depends on the policy.
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if original.IsNil() {
+			return nil
+		}
+		switch {
+		case original.Kind() == reflect.Chan && c.chans != UncopyableChans:
+			return c.copyChan(original, cpy)
+		case original.Kind() == reflect.Func && c.funcs != UncopyableFuncs:
+			if c.funcs == ShareFuncs {
+				cpy.Set(original)
+			}
+			return nil
+		}
+		switch c.uncopyable {
+		case Strict:
+			return &UncopyableError{Type: c.typ, Path: c.path.clone(), Kind: original.Kind()}
+		case Share:
+			cpy.Set(original)
+		}
 	// Set the actual values from here on.
 	case reflect.String:
 		cpy.SetString(original.String())
@@ -130,4 +599,5 @@ func copyRecursive(original, cpy reflect.Value) {
 	default:
 		cpy.Set(original)
 	}
+	return nil
 }
@@ -1,6 +1,8 @@
 package deepcopy
 
 import (
+	"reflect"
+	"sync"
 	"testing"
 
 	json "github.com/mohae/customjson"
@@ -176,3 +178,407 @@ func TestCanSet(t *testing.T) {
 		t.Errorf("Expected copy to be %#v, got %#v\n", expected, cpy)
 	}
 }
+
+// node is used for testing graphs that contain cycles.
+type node struct {
+	Name     string
+	Parent   *node
+	Children []*node
+}
+
+func TestCycles(t *testing.T) {
+	root := &node{Name: "root"}
+	child := &node{Name: "child", Parent: root}
+	root.Children = []*node{child, child}
+	cpy := Iface(root).(*node)
+	if cpy == root {
+		t.Fatal("expected the copy to be a new node, got the original")
+	}
+	if cpy.Name != "root" {
+		t.Errorf("expected root name to be \"root\", got %q", cpy.Name)
+	}
+	if len(cpy.Children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(cpy.Children))
+	}
+	if cpy.Children[0] == child {
+		t.Error("expected the child to be copied, got the original")
+	}
+	if cpy.Children[0].Parent != cpy {
+		t.Error("expected the child's parent to be the copied root")
+	}
+	if cpy.Children[0] != cpy.Children[1] {
+		t.Error("expected both children to point at the same copied node")
+	}
+
+	m := map[string]interface{}{"name": "self"}
+	m["self"] = m
+	mcpy := Iface(m).(map[string]interface{})
+	self, ok := mcpy["self"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected self to be a map[string]interface{}, got %T", mcpy["self"])
+	}
+	if reflect.ValueOf(self).Pointer() != reflect.ValueOf(mcpy).Pointer() {
+		t.Error("expected the copied map to refer to itself")
+	}
+	if reflect.ValueOf(self).Pointer() == reflect.ValueOf(m).Pointer() {
+		t.Error("expected the copied map to not refer to the original")
+	}
+
+	s := make([]interface{}, 2)
+	s[0] = s
+	s[1] = s[:1]
+	scpy := Iface(s).([]interface{})
+	if reflect.ValueOf(scpy).Pointer() == reflect.ValueOf(s).Pointer() {
+		t.Fatal("expected the slice to be copied, got the original")
+	}
+	if inner := scpy[0].([]interface{}); reflect.ValueOf(inner).Pointer() != reflect.ValueOf(scpy).Pointer() || len(inner) != 2 {
+		t.Error("expected the copied slice to contain itself")
+	}
+	// A shorter slice of the same memory is copied on its own.
+	if inner := scpy[1].([]interface{}); len(inner) != 1 || reflect.ValueOf(inner).Pointer() == reflect.ValueOf(s).Pointer() {
+		t.Errorf("expected a copy of the first element, got %v", inner)
+	}
+}
+
+func TestAliasing(t *testing.T) {
+	type pair struct {
+		A *int
+		B *int
+		C map[string]int
+		D map[string]int
+	}
+	i := 42
+	m := map[string]int{"a": 1}
+	p := pair{A: &i, B: &i, C: m, D: m}
+	cpy := Iface(p).(pair)
+	if cpy.A == &i {
+		t.Error("expected A to be copied, got the original pointer")
+	}
+	if cpy.A != cpy.B {
+		t.Error("expected A and B to point at the same copied int")
+	}
+	if *cpy.A != 42 {
+		t.Errorf("expected 42, got %d", *cpy.A)
+	}
+	cpy.C["b"] = 2
+	if _, ok := cpy.D["b"]; !ok {
+		t.Error("expected C and D to be the same copied map")
+	}
+	if _, ok := m["b"]; ok {
+		t.Error("expected the original map to be unchanged")
+	}
+
+	type slices struct {
+		A []*int
+		B []*int
+	}
+	ps := []*int{&i}
+	scpy := Iface(slices{ps, ps}).(slices)
+	if &scpy.A[0] != &scpy.B[0] {
+		t.Error("expected A and B to be the same copied slice")
+	}
+	if &scpy.A[0] == &ps[0] {
+		t.Error("expected the slice to be copied, got the original")
+	}
+}
+
+func TestIfaceE(t *testing.T) {
+	type Config struct {
+		Name  string
+		Hooks []func()
+		Done  chan struct{}
+	}
+	done := make(chan struct{})
+	cfg := Config{Name: "cfg", Hooks: []func(){nil, nil, func() {}}, Done: done}
+
+	_, err := IfaceE(cfg)
+	if err == nil {
+		t.Fatal("expected an error, got none")
+	}
+	uerr, ok := err.(*UncopyableError)
+	if !ok {
+		t.Fatalf("expected an *UncopyableError, got %T", err)
+	}
+	if uerr.Kind != reflect.Func {
+		t.Errorf("expected kind func, got %s", uerr.Kind)
+	}
+	if s := uerr.Path.in(uerr.Type); s != "Config.Hooks[2]" {
+		t.Errorf("expected path Config.Hooks[2], got %s", s)
+	}
+	if err.Error() != "deepcopy: cannot copy func at Config.Hooks[2]" {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	v, err := IfaceE(cfg, OnUncopyable(Skip))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cpy := v.(Config)
+	if cpy.Name != "cfg" {
+		t.Errorf("expected name to be \"cfg\", got %q", cpy.Name)
+	}
+	if cpy.Hooks[2] != nil || cpy.Done != nil {
+		t.Error("expected the func and the channel to be skipped")
+	}
+
+	v, err = IfaceE(cfg, OnUncopyable(Share))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cpy = v.(Config)
+	if cpy.Hooks[2] == nil {
+		t.Error("expected the func to be shared")
+	}
+	if cpy.Done != done {
+		t.Error("expected the channel to be shared")
+	}
+
+	// nil values can always be copied.
+	_, err = IfaceE(Config{Hooks: []func(){nil}})
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestChanAndFuncPolicies(t *testing.T) {
+	type worker struct {
+		Jobs    chan int
+		Results <-chan int
+		Handle  func()
+	}
+	jobs := make(chan int, 4)
+	jobs <- 1
+	w := &worker{Jobs: jobs, Results: jobs, Handle: func() {}}
+
+	v, err := IfaceE(w, OnChan(NewChans), OnFunc(ShareFuncs))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cpy := v.(*worker)
+	if cpy.Jobs == jobs || cap(cpy.Jobs) != 4 || len(cpy.Jobs) != 0 {
+		t.Errorf("expected a new, empty channel with a capacity of 4, got one with %d of %d", len(cpy.Jobs), cap(cpy.Jobs))
+	}
+	cpy.Jobs <- 2
+	if len(cpy.Results) != 1 {
+		t.Error("expected both views of the channel to be the same new channel")
+	}
+	if cpy.Handle == nil {
+		t.Error("expected the func to be shared")
+	}
+
+	// The policies override OnUncopyable for channels and funcs alone.
+	v, err = IfaceE(w, OnChan(NilChans), OnFunc(NilFuncs))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cpy = v.(*worker)
+	if cpy.Jobs != nil || cpy.Results != nil || cpy.Handle != nil {
+		t.Errorf("expected the channels and the func to be nil, got %+v", cpy)
+	}
+	v, err = IfaceE(w, OnUncopyable(Skip), OnChan(ShareChans))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cpy = v.(*worker)
+	if cpy.Jobs != jobs || cpy.Handle != nil {
+		t.Errorf("expected the channel to be shared and the func skipped, got %+v", cpy)
+	}
+	_, err = IfaceE(w, OnChan(ShareChans))
+	if uerr, ok := err.(*UncopyableError); !ok || uerr.Kind != reflect.Func {
+		t.Errorf("expected an *UncopyableError for the func, got %v", err)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ints := []int{1, 2, 3}
+	icpy := Copy(ints)
+	icpy[0] = 42
+	if ints[0] != 1 {
+		t.Errorf("expected the original to be unchanged, got %v", ints)
+	}
+
+	root := &node{Name: "root"}
+	root.Children = []*node{{Name: "child", Parent: root}}
+	ncpy := Copy(root)
+	if ncpy == root || ncpy.Children[0].Parent != ncpy {
+		t.Error("expected the node to be deep copied")
+	}
+
+	var iface interface{} = map[string]int{"a": 1}
+	ifcpy := Copy(iface)
+	ifcpy.(map[string]int)["a"] = 2
+	if iface.(map[string]int)["a"] != 1 {
+		t.Errorf("expected the original to be unchanged, got %v", iface)
+	}
+
+	m := map[string][]string{"a": {"b"}}
+	dst := map[string][]string{"c": {"d"}}
+	CopyInto(&dst, m)
+	if len(dst) != 1 || dst["a"][0] != "b" {
+		t.Errorf("expected %v, got %v", m, dst)
+	}
+	dst["a"][0] = "z"
+	if m["a"][0] != "b" {
+		t.Errorf("expected the original to be unchanged, got %v", m)
+	}
+}
+
+func TestCopyUnexported(t *testing.T) {
+	type account struct {
+		Name    string
+		balance int
+		history []int
+		meta    map[string]interface{}
+		mu      sync.Mutex
+	}
+	a := &account{Name: "a", balance: 42, history: []int{1, 2}, meta: map[string]interface{}{"t": TestStruct{strings: "x"}}}
+	a.mu.Lock()
+	v, err := IfaceE(a, CopyUnexported())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cpy := v.(*account)
+	if cpy.balance != 42 {
+		t.Errorf("expected balance to be 42, got %d", cpy.balance)
+	}
+	if len(cpy.history) != 2 || cpy.history[1] != 2 {
+		t.Errorf("expected history to be [1 2], got %v", cpy.history)
+	}
+	a.history[0] = 11
+	if cpy.history[0] != 1 {
+		t.Error("expected history to be deep copied")
+	}
+	if s := cpy.meta["t"].(TestStruct).strings; s != "x" {
+		t.Errorf("expected the unexported field within the map to be \"x\", got %q", s)
+	}
+	if !cpy.mu.TryLock() {
+		t.Error("expected the copied mutex to be unlocked")
+	}
+
+	// The same value copied without the option loses its unexported fields.
+	cpy = Iface(a).(*account)
+	if cpy.balance != 0 || cpy.history != nil {
+		t.Errorf("expected unexported fields to be zero, got %#v", cpy)
+	}
+
+	// Struct values, not just pointers to them, can be copied.
+	v, err = IfaceE(TestStruct{strings: "s", iSlice: []int{1}}, CopyUnexported())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ts := v.(TestStruct); ts.strings != "s" || ts.iSlice[0] != 1 {
+		t.Errorf("expected the unexported fields to be copied, got %#v", ts)
+	}
+}
+
+func TestNils(t *testing.T) {
+	type lists struct {
+		NilSlice   []int
+		EmptySlice []int
+		NilMap     map[string]int
+		EmptyMap   map[string]int
+		Full       []string
+	}
+	l := lists{EmptySlice: []int{}, EmptyMap: map[string]int{}, Full: []string{"a"}}
+	tests := []struct {
+		policy             NilPolicy
+		nilSlice, nilMap   bool
+		emptySlice, empMap bool
+	}{
+		{PreserveNil, true, true, false, false},
+		{NilToEmpty, false, false, false, false},
+		{EmptyToNil, true, true, true, true},
+	}
+	for i, test := range tests {
+		cpy := Copy(l, OnNil(test.policy))
+		if (cpy.NilSlice == nil) != test.nilSlice {
+			t.Errorf("%d: expected NilSlice to be nil: %t, got %#v", i, test.nilSlice, cpy.NilSlice)
+		}
+		if (cpy.NilMap == nil) != test.nilMap {
+			t.Errorf("%d: expected NilMap to be nil: %t, got %#v", i, test.nilMap, cpy.NilMap)
+		}
+		if (cpy.EmptySlice == nil) != test.emptySlice {
+			t.Errorf("%d: expected EmptySlice to be nil: %t, got %#v", i, test.emptySlice, cpy.EmptySlice)
+		}
+		if (cpy.EmptyMap == nil) != test.empMap {
+			t.Errorf("%d: expected EmptyMap to be nil: %t, got %#v", i, test.empMap, cpy.EmptyMap)
+		}
+		if len(cpy.Full) != 1 || cpy.Full[0] != "a" {
+			t.Errorf("%d: expected Full to be [a], got %v", i, cpy.Full)
+		}
+	}
+	if !reflect.DeepEqual(Iface(l), l) {
+		t.Errorf("expected the copy to be DeepEqual to %#v", l)
+	}
+
+	dst := map[string][]string{"c": {"d"}}
+	CopyInto(&dst, nil)
+	if dst != nil {
+		t.Errorf("expected copying nil to result in nil, got %v", dst)
+	}
+}
+
+func TestArrays(t *testing.T) {
+	type grid struct {
+		Cells [2][]int
+		Ptrs  [2]*node
+		Maps  [1]map[string]int
+	}
+	n := &node{Name: "n"}
+	g := grid{
+		Cells: [2][]int{{1, 2}, {3}},
+		Ptrs:  [2]*node{n, n},
+		Maps:  [1]map[string]int{{"a": 1}},
+	}
+	cpy := Copy(g)
+	g.Cells[0][0] = 42
+	g.Maps[0]["a"] = 42
+	if cpy.Cells[0][0] != 1 {
+		t.Errorf("expected the slice within the array to be copied, got %v", cpy.Cells)
+	}
+	if cpy.Maps[0]["a"] != 1 {
+		t.Errorf("expected the map within the array to be copied, got %v", cpy.Maps)
+	}
+	if cpy.Ptrs[0] == n || cpy.Ptrs[0] != cpy.Ptrs[1] {
+		t.Error("expected both pointers to point at the same copied node")
+	}
+
+	arr := [3]int{1, 2, 3}
+	if Copy(arr) != arr {
+		t.Errorf("expected %v, got %v", arr, Copy(arr))
+	}
+}
+
+func TestCopyMapKeys(t *testing.T) {
+	k := &node{Name: "key"}
+	m := map[*node]*node{k: k}
+	cpy := Copy(m)
+	if _, ok := cpy[k]; !ok {
+		t.Error("expected the original key to be used by default")
+	}
+	cpy = Copy(m, CopyMapKeys())
+	if len(cpy) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(cpy))
+	}
+	for key, v := range cpy {
+		if key == k {
+			t.Error("expected the key to be copied")
+		}
+		if key != v {
+			t.Error("expected the key and value to be the same copied node")
+		}
+		if key.Name != "key" {
+			t.Errorf("expected the key's name to be \"key\", got %q", key.Name)
+		}
+	}
+
+	im := map[interface{}]int{[1]*int{new(int)}: 1}
+	icpy := Copy(im, CopyMapKeys())
+	for key := range icpy {
+		for orig := range im {
+			if key.([1]*int)[0] == orig.([1]*int)[0] {
+				t.Error("expected the pointer within the interface key to be copied")
+			}
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// Added is a slice or array element or a map entry that is only in the
+	// new value.
+	Added ChangeKind = iota + 1
+	// Removed is a slice or array element or a map entry that is only in
+	// the old value.
+	Removed
+	// Modified is a value that is in both, but differs.
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a difference between two values.
+type Change struct {
+	Path Path        // where the values differ
+	Kind ChangeKind  // how they differ
+	Old  interface{} // the old value; nil when Added
+	New  interface{} // the new value; nil when Removed
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added %v", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("%s: removed %v", c.Path, c.Old)
+	}
+	return fmt.Sprintf("%s: %v => %v", c.Path, c.Old, c.New)
+}
+
+// DiffOption configures how values are compared by Diff.
+type DiffOption func(*differ)
+
+// IgnorePaths ignores the values at paths, and everything within them.
+// Paths are written the way Path.String writes them; "[*]" matches any
+// index or key, e.g. Servers[*].Stats.
+func IgnorePaths(paths ...string) DiffOption {
+	return func(d *differ) {
+		for _, p := range paths {
+			d.ignore = append(d.ignore, compilePattern(p))
+		}
+	}
+}
+
+// IgnoreTag ignores struct fields whose key tag has value as one of its
+// comma separated options, e.g. IgnoreTag("diff", "-") ignores fields
+// tagged `diff:"-"`.
+func IgnoreTag(key, value string) DiffOption {
+	return func(d *differ) {
+		d.tags = append(d.tags, [2]string{key, value})
+	}
+}
+
+// Diff returns the changes between a and b, walking both the same way Iface
+// copies: pointers and interfaces are followed and exported struct fields,
+// slice and array elements, and map entries are compared. Values whose
+// types have a copier, e.g. time.Time, are compared as a whole.
+//
+// Changes are returned in the order they are found; map entries are visited
+// in the order of their keys. A nil and an empty slice or map differ.
+func Diff(a, b interface{}, opts ...DiffOption) []Change {
+	d := &differ{visited: make(map[visitPair]bool)}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.diff(reflect.ValueOf(a), reflect.ValueOf(b))
+	return d.changes
+}
+
+// visitPair identifies a pair of pointers, maps, or slices that are being,
+// or have been, compared.
+type visitPair struct {
+	a, b       uintptr
+	typ        reflect.Type
+	alen, blen int // for slices
+}
+
+// differ holds the state of a single Diff.
+type differ struct {
+	ignore  []pattern
+	tags    [][2]string
+	path    Path
+	visited map[visitPair]bool
+	changes []Change
+}
+
+// add records a change at the current path.
+func (d *differ) add(kind ChangeKind, a, b reflect.Value) {
+	d.changes = append(d.changes, Change{Path: d.path.clone(), Kind: kind, Old: valueOf(a), New: valueOf(b)})
+}
+
+// valueOf returns v as an interface{}, or nil if it isn't valid or can't be
+// interfaced.
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// ignored reports whether the current path is to be ignored.
+func (d *differ) ignored() bool {
+	for _, pt := range d.ignore {
+		if pt.match(d.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredField reports whether f is to be ignored because of its tags.
+func (d *differ) ignoredField(f reflect.StructField) bool {
+	for _, t := range d.tags {
+		for _, opt := range strings.Split(f.Tag.Get(t[0]), ",") {
+			if strings.TrimSpace(opt) == t[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// diff compares a and b, which are at the current path.
+func (d *differ) diff(a, b reflect.Value) {
+	if d.ignored() {
+		return
+	}
+	if !a.IsValid() || !b.IsValid() {
+		if a.IsValid() != b.IsValid() {
+			d.add(Modified, a, b)
+		}
+		return
+	}
+	if a.Type() != b.Type() {
+		d.add(Modified, a, b)
+		return
+	}
+	// Types that have a copier have state that isn't in their exported
+	// fields, so they are compared as a whole.
+	if planFor(a.Type()).copier != nil && a.CanInterface() {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			d.add(Modified, a, b)
+		}
+		return
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(Modified, a, b)
+			}
+			return
+		}
+		if d.seen(a, b) {
+			return
+		}
+		d.diff(a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(Modified, a, b)
+			}
+			return
+		}
+		d.diff(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			f := a.Type().Field(i)
+			if !f.IsExported() || d.ignoredField(f) {
+				continue
+			}
+			d.path = append(d.path, Step{Kind: FieldStep, Name: f.Name, Tag: f.Tag})
+			d.diff(a.Field(i), b.Field(i))
+			d.path = d.path[:len(d.path)-1]
+		}
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() {
+			d.add(Modified, a, b)
+			return
+		}
+		if d.seen(a, b) {
+			return
+		}
+		d.diffElems(a, b)
+	case reflect.Array:
+		d.diffElems(a, b)
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			d.add(Modified, a, b)
+			return
+		}
+		if d.seen(a, b) {
+			return
+		}
+		d.diffMaps(a, b)
+	case reflect.Bool:
+		if a.Bool() != b.Bool() {
+			d.add(Modified, a, b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if a.Int() != b.Int() {
+			d.add(Modified, a, b)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if a.Uint() != b.Uint() {
+			d.add(Modified, a, b)
+		}
+	case reflect.Float32, reflect.Float64:
+		if a.Float() != b.Float() {
+			d.add(Modified, a, b)
+		}
+	case reflect.Complex64, reflect.Complex128:
+		if a.Complex() != b.Complex() {
+			d.add(Modified, a, b)
+		}
+	case reflect.String:
+		if a.String() != b.String() {
+			d.add(Modified, a, b)
+		}
+	// Channels, funcs, and unsafe.Pointers can only be compared by what
+	// they point at.
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if a.Pointer() != b.Pointer() {
+			d.add(Modified, a, b)
+		}
+	}
+}
+
+// seen reports whether a and b, pointers, maps, or slices, have already
+// been compared, recording them if they haven't. This stops cycles.
+func (d *differ) seen(a, b reflect.Value) bool {
+	key := newVisitPair(a, b)
+	if d.visited[key] {
+		return true
+	}
+	d.visited[key] = true
+	return false
+}
+
+// newVisitPair returns the visitPair for a and b, pointers, maps, or
+// slices of the same type.
+func newVisitPair(a, b reflect.Value) visitPair {
+	key := visitPair{a: a.Pointer(), b: b.Pointer(), typ: a.Type()}
+	if a.Kind() == reflect.Slice {
+		key.alen, key.blen = a.Len(), b.Len()
+	}
+	return key
+}
+
+// diffElems compares the elements of the slices or arrays a and b.
+func (d *differ) diffElems(a, b reflect.Value) {
+	n := a.Len()
+	if b.Len() > n {
+		n = b.Len()
+	}
+	for i := 0; i < n; i++ {
+		d.path = append(d.path, Step{Kind: IndexStep, Index: i})
+		switch {
+		case i >= b.Len():
+			if !d.ignored() {
+				d.add(Removed, a.Index(i), reflect.Value{})
+			}
+		case i >= a.Len():
+			if !d.ignored() {
+				d.add(Added, reflect.Value{}, b.Index(i))
+			}
+		default:
+			d.diff(a.Index(i), b.Index(i))
+		}
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// diffMaps compares the entries of the maps a and b.
+func (d *differ) diffMaps(a, b reflect.Value) {
+	for _, key := range sortedKeys(a, b) {
+		d.path = append(d.path, Step{Kind: KeyStep, Key: key})
+		av, bv := a.MapIndex(key), b.MapIndex(key)
+		switch {
+		case !bv.IsValid():
+			if !d.ignored() {
+				d.add(Removed, av, reflect.Value{})
+			}
+		case !av.IsValid():
+			if !d.ignored() {
+				d.add(Added, reflect.Value{}, bv)
+			}
+		default:
+			d.diff(av, bv)
+		}
+		d.path = d.path[:len(d.path)-1]
+	}
+}
+
+// sortedKeys returns the keys of the maps a and b, without duplicates, in
+// order.
+func sortedKeys(a, b reflect.Value) []reflect.Value {
+	keys := a.MapKeys()
+	for _, key := range b.MapKeys() {
+		if !a.MapIndex(key).IsValid() {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	return keys
+}
+
+// keyLess reports whether the map key a sorts before b. Numbers and strings
+// sort by value; everything else by how it is formatted.
+func keyLess(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Interface {
+		a, b = a.Elem(), b.Elem()
+		if !a.IsValid() || !b.IsValid() {
+			return !a.IsValid() && b.IsValid()
+		}
+		if a.Type() != b.Type() {
+			return a.Type().String() < b.Type().String()
+		}
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	}
+	return formatKey(a) < formatKey(b)
+}
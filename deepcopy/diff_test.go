@@ -0,0 +1,135 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type server struct {
+	Name    string
+	Ports   map[string]int
+	Tags    []string
+	Started time.Time
+	Stats   *stats `diff:"-"`
+	Backup  *server
+}
+
+type stats struct {
+	Requests int
+}
+
+func TestDiff(t *testing.T) {
+	type config struct {
+		Servers []server
+		Debug   bool
+	}
+	now := time.Now()
+	a := config{
+		Servers: []server{
+			{Name: "a", Ports: map[string]int{"http": 80}},
+			{Name: "b", Ports: map[string]int{"http": 80, "https": 443}, Tags: []string{"x", "y"}, Started: now},
+		},
+	}
+	b := Copy(a)
+	b.Debug = true
+	b.Servers[1].Ports["http"] = 8080
+	delete(b.Servers[1].Ports, "https")
+	b.Servers[1].Ports["ssh"] = 22
+	b.Servers[1].Tags = b.Servers[1].Tags[:1]
+	b.Servers[1].Started = now.Add(time.Second)
+	b.Servers = append(b.Servers, server{Name: "c"})
+
+	expected := []struct {
+		path string
+		kind ChangeKind
+		old  interface{}
+		new  interface{}
+	}{
+		{`Servers[1].Ports["http"]`, Modified, 80, 8080},
+		{`Servers[1].Ports["https"]`, Removed, 443, nil},
+		{`Servers[1].Ports["ssh"]`, Added, nil, 22},
+		{`Servers[1].Tags[1]`, Removed, "y", nil},
+		{`Servers[1].Started`, Modified, now, now.Add(time.Second)},
+		{`Servers[2]`, Added, nil, server{Name: "c"}},
+		{`Debug`, Modified, false, true},
+	}
+	changes := Diff(a, b)
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+	for i, c := range changes {
+		e := expected[i]
+		if c.Path.String() != e.path || c.Kind != e.kind || !reflect.DeepEqual(c.Old, e.old) || !reflect.DeepEqual(c.New, e.new) {
+			t.Errorf("%d: expected %s %s %v %v, got %s %s %v %v", i, e.path, e.kind, e.old, e.new, c.Path, c.Kind, c.Old, c.New)
+		}
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("expected no changes between a value and itself, got %v", changes)
+	}
+	if changes := Diff(a, b, IgnorePaths("Servers[*].Ports", "Servers[1].Started", "Servers[2]", "Debug"), IgnoreTag("diff", "-")); len(changes) != 1 || changes[0].Path.String() != "Servers[1].Tags[1]" {
+		t.Errorf("expected only the tag change to not be ignored, got %v", changes)
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	a := server{Name: "a", Stats: &stats{Requests: 1}}
+	b := server{Name: "a", Stats: &stats{Requests: 2}}
+	if changes := Diff(a, b); len(changes) != 1 || changes[0].Path.String() != "Stats.Requests" {
+		t.Errorf("expected Stats.Requests to change, got %v", changes)
+	}
+	if changes := Diff(a, b, IgnoreTag("diff", "-")); len(changes) != 0 {
+		t.Errorf("expected the tagged field to be ignored, got %v", changes)
+	}
+	if changes := Diff(a, b, IgnorePaths("Stats")); len(changes) != 0 {
+		t.Errorf("expected the path to be ignored, got %v", changes)
+	}
+}
+
+func TestDiffNilsAndTypes(t *testing.T) {
+	tests := []struct {
+		a, b    interface{}
+		changes int
+	}{
+		{nil, nil, 0},
+		{nil, 1, 1},
+		{1, "1", 1},
+		{[]int(nil), []int{}, 1},
+		{map[string]int(nil), map[string]int{}, 1},
+		{[]interface{}{1, "a"}, []interface{}{1, 2}, 1},
+		{map[int]string{2: "b", 10: "a"}, map[int]string{2: "c", 10: "d"}, 2},
+		{(*int)(nil), new(int), 1},
+	}
+	for i, test := range tests {
+		changes := Diff(test.a, test.b)
+		if len(changes) != test.changes {
+			t.Errorf("%d: expected %d changes, got %v", i, test.changes, changes)
+		}
+	}
+	changes := Diff(map[int]string{2: "b", 10: "a"}, map[int]string{2: "c", 10: "d"})
+	if changes[0].Path.String() != "[2]" || changes[1].Path.String() != "[10]" {
+		t.Errorf("expected the changes to be in key order, got %v", changes)
+	}
+}
+
+func TestDiffCycles(t *testing.T) {
+	a := &server{Name: "a"}
+	a.Backup = a
+	b := &server{Name: "b"}
+	b.Backup = b
+	changes := Diff(a, b)
+	if len(changes) != 1 || changes[0].Path.String() != "Name" {
+		t.Errorf("expected only Name to change, got %v", changes)
+	}
+
+	slice := func(names ...interface{}) []interface{} {
+		return append([]interface{}{nil}, names...)
+	}
+	s, s2 := slice("a"), slice("b", "c")
+	s[0], s2[0] = s, s2
+	changes = Diff(s, s2)
+	if len(changes) != 2 || changes[0].Path.String() != "[1]" || changes[1].Path.String() != "[2]" {
+		t.Errorf("expected [1] and [2] to change, got %v", changes)
+	}
+}
@@ -0,0 +1,274 @@
+package deepcopy
+
+import (
+	"math"
+	"reflect"
+)
+
+// EqualOption configures how values are compared by Equal.
+type EqualOption func(*equaler)
+
+// IgnoreFields ignores the values at paths, and everything within them, as
+// IgnorePaths does for Diff.
+func IgnoreFields(paths ...string) EqualOption {
+	return func(e *equaler) {
+		for _, p := range paths {
+			e.ignore = append(e.ignore, compilePattern(p))
+		}
+	}
+}
+
+// NilEqualsEmpty treats nil slices and maps as equal to empty ones.
+func NilEqualsEmpty() EqualOption {
+	return func(e *equaler) {
+		e.nilEmpty = true
+	}
+}
+
+// FloatTolerance treats floats, and the parts of complex numbers, as equal
+// when they differ by no more than tolerance. NaN is never equal to
+// anything.
+func FloatTolerance(tolerance float64) EqualOption {
+	return func(e *equaler) {
+		e.tolerance = tolerance
+	}
+}
+
+// CompareUnexported compares the unexported fields of structs of the given
+// types too, or of all structs if no types are given. By default, only
+// exported fields are compared, as only they are copied by default.
+func CompareUnexported(types ...reflect.Type) EqualOption {
+	return func(e *equaler) {
+		if len(types) == 0 {
+			e.unexported = true
+			return
+		}
+		if e.unexportedTypes == nil {
+			e.unexportedTypes = make(map[reflect.Type]bool)
+		}
+		for _, t := range types {
+			e.unexportedTypes[t] = true
+		}
+	}
+}
+
+// Comparer compares values of type T with fn instead of by what they
+// contain, e.g. time.Time values with their Equal method.
+func Comparer[T any](fn func(a, b T) bool) EqualOption {
+	return func(e *equaler) {
+		if e.comparers == nil {
+			e.comparers = make(map[reflect.Type]func(a, b reflect.Value) bool)
+		}
+		e.comparers[reflect.TypeOf((*T)(nil)).Elem()] = func(a, b reflect.Value) bool {
+			return fn(a.Interface().(T), b.Interface().(T))
+		}
+	}
+}
+
+// Equal reports whether a and b are deeply equal, walking them the same way
+// Iface copies: pointers and interfaces are followed and exported struct
+// fields, slice and array elements, and map entries are compared. Values
+// whose types have a copier, e.g. time.Time, are compared as a whole, with
+// reflect.DeepEqual, unless there is a Comparer for them. Channels, funcs,
+// and unsafe.Pointers are equal when they point at the same thing.
+//
+// Without options, Equal is like reflect.DeepEqual, except that unexported
+// fields aren't compared and funcs can be equal.
+func Equal(a, b interface{}, opts ...EqualOption) bool {
+	e := &equaler{visited: make(map[visitPair]bool)}
+	for _, opt := range opts {
+		opt(e)
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if e.unlocks() {
+		va, vb = addressable(va), addressable(vb)
+	}
+	return e.equal(va, vb)
+}
+
+// equaler holds the state of a single Equal.
+type equaler struct {
+	ignore          []pattern
+	nilEmpty        bool
+	tolerance       float64
+	unexported      bool
+	unexportedTypes map[reflect.Type]bool
+	comparers       map[reflect.Type]func(a, b reflect.Value) bool
+	path            Path
+	visited         map[visitPair]bool
+}
+
+// unlocks reports whether unexported fields may be compared, in which case
+// values are kept addressable so that those fields can be unlocked.
+func (e *equaler) unlocks() bool {
+	return e.unexported || len(e.unexportedTypes) > 0
+}
+
+// ignored reports whether the current path is to be ignored.
+func (e *equaler) ignored() bool {
+	for _, pt := range e.ignore {
+		if pt.match(e.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// seen reports whether a and b, pointers, maps, or slices, have already
+// been compared, recording them if they haven't. This stops cycles.
+func (e *equaler) seen(a, b reflect.Value) bool {
+	key := newVisitPair(a, b)
+	if e.visited[key] {
+		return true
+	}
+	e.visited[key] = true
+	return false
+}
+
+// equal reports whether a and b, which are at the current path, are equal.
+func (e *equaler) equal(a, b reflect.Value) bool {
+	if e.ignored() {
+		return true
+	}
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+	if fn := e.comparers[a.Type()]; fn != nil {
+		return fn(a, b)
+	}
+	// Types that have a copier have state that isn't in their exported
+	// fields, so they are compared as a whole.
+	if planFor(a.Type()).copier != nil {
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Pointer() == b.Pointer() || e.seen(a, b) {
+			return true
+		}
+		return e.equal(a.Elem(), b.Elem())
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return e.equal(e.elem(a.Elem()), e.elem(b.Elem()))
+	case reflect.Struct:
+		compareUnexported := e.unexported || e.unexportedTypes[a.Type()]
+		for _, f := range planFor(a.Type()).fields {
+			af, bf := a.Field(f.index), b.Field(f.index)
+			if !f.exported {
+				if !compareUnexported {
+					continue
+				}
+				af, bf = unlock(af), unlock(bf)
+			}
+			e.path = append(e.path, f.step())
+			if !e.equal(af, bf) {
+				return false
+			}
+			e.path = e.path[:len(e.path)-1]
+		}
+		return true
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() && !(e.nilEmpty && a.Len() == 0 && b.Len() == 0) {
+			return false
+		}
+		if a.Len() == b.Len() && (a.Pointer() == b.Pointer() || e.seen(a, b)) {
+			return true
+		}
+		return e.equalElems(a, b)
+	case reflect.Array:
+		return e.equalElems(a, b)
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() && !(e.nilEmpty && a.Len() == 0 && b.Len() == 0) {
+			return false
+		}
+		if a.Pointer() == b.Pointer() || e.seen(a, b) {
+			return true
+		}
+		return e.equalMaps(a, b)
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return e.equalFloats(a.Float(), b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		return e.equalFloats(real(a.Complex()), real(b.Complex())) && e.equalFloats(imag(a.Complex()), imag(b.Complex()))
+	case reflect.String:
+		return a.String() == b.String()
+	// Channels, funcs, and unsafe.Pointers can only be compared by what
+	// they point at.
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	}
+	return false
+}
+
+// elem returns v, what an interface or a map entry holds, as it is to be
+// compared.
+func (e *equaler) elem(v reflect.Value) reflect.Value {
+	if e.unlocks() {
+		return addressable(v)
+	}
+	return v
+}
+
+// equalFloats reports whether a and b are equal within the tolerance.
+func (e *equaler) equalFloats(a, b float64) bool {
+	return a == b || math.Abs(a-b) <= e.tolerance
+}
+
+// equalElems reports whether the slices or arrays a and b have equal
+// elements.
+func (e *equaler) equalElems(a, b reflect.Value) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	for i := 0; i < a.Len(); i++ {
+		e.path = append(e.path, Step{Kind: IndexStep, Index: i})
+		if !e.equal(a.Index(i), b.Index(i)) {
+			return false
+		}
+		e.path = e.path[:len(e.path)-1]
+	}
+	return true
+}
+
+// equalMaps reports whether the maps a and b have the same keys with equal
+// values. Entries that are only in one of them are fine if they are
+// ignored.
+func (e *equaler) equalMaps(a, b reflect.Value) bool {
+	for iter := a.MapRange(); iter.Next(); {
+		e.path = append(e.path, Step{Kind: KeyStep, Key: iter.Key()})
+		bv := b.MapIndex(iter.Key())
+		if !bv.IsValid() {
+			if !e.ignored() {
+				return false
+			}
+		} else if !e.equal(e.elem(iter.Value()), e.elem(bv)) {
+			return false
+		}
+		e.path = e.path[:len(e.path)-1]
+	}
+	for iter := b.MapRange(); iter.Next(); {
+		if a.MapIndex(iter.Key()).IsValid() {
+			continue
+		}
+		e.path = append(e.path, Step{Kind: KeyStep, Key: iter.Key()})
+		ignored := e.ignored()
+		e.path = e.path[:len(e.path)-1]
+		if !ignored {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEqual(t *testing.T) {
+	type point struct {
+		X, Y float64
+		id   int
+	}
+	type shape struct {
+		Name    string
+		Points  []point
+		Labels  map[string]string
+		Created time.Time
+		Updated time.Time
+		Fn      func()
+	}
+	fn := func() {}
+	est := time.FixedZone("EST", -5*60*60)
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	a := shape{
+		Name:    "a",
+		Points:  []point{{1, 2, 1}, {3, 4, 2}},
+		Labels:  map[string]string{"k": "v"},
+		Created: created,
+		Updated: created,
+		Fn:      fn,
+	}
+	with := func(f func(s *shape)) shape {
+		b := Copy(a)
+		f(&b)
+		return b
+	}
+	tests := []struct {
+		name     string
+		b        interface{}
+		opts     []EqualOption
+		expected bool
+	}{
+		{"copy", Copy(a), nil, true},
+		{"different type", &a, nil, false},
+		{"different string", with(func(s *shape) { s.Name = "b" }), nil, false},
+		{"ignored field", with(func(s *shape) { s.Name = "b" }), []EqualOption{IgnoreFields("Name")}, true},
+		{"ignored element", with(func(s *shape) { s.Points[1].X = 5 }), []EqualOption{IgnoreFields("Points[*].X")}, true},
+		{"extra key", with(func(s *shape) { s.Labels["x"] = "y" }), nil, false},
+		{"ignored extra key", with(func(s *shape) { s.Labels["x"] = "y" }), []EqualOption{IgnoreFields(`Labels["x"]`)}, true},
+		{"nil and empty", with(func(s *shape) { s.Labels = map[string]string{} }), nil, false},
+		{"float", with(func(s *shape) { s.Points[0].X += 1e-9 }), nil, false},
+		{"float within tolerance", with(func(s *shape) { s.Points[0].X += 1e-9 }), []EqualOption{FloatTolerance(1e-6)}, true},
+		{"float outside tolerance", with(func(s *shape) { s.Points[0].X += 1e-3 }), []EqualOption{FloatTolerance(1e-6)}, false},
+		{"unexported", with(func(s *shape) { s.Points[0].id = 9 }), nil, true},
+		{"compare unexported", with(func(s *shape) { s.Points[0].id = 9 }), []EqualOption{CompareUnexported()}, false},
+		{"compare unexported of type", with(func(s *shape) { s.Points[0].id = 9 }), []EqualOption{CompareUnexported(reflect.TypeOf(point{}))}, false},
+		{"compare unexported of other type", with(func(s *shape) { s.Points[0].id = 9 }), []EqualOption{CompareUnexported(reflect.TypeOf(shape{}))}, true},
+		{"time in another zone", with(func(s *shape) { s.Created = created.In(est) }), nil, false},
+		{"time comparer", with(func(s *shape) { s.Created = created.In(est) }), []EqualOption{Comparer(time.Time.Equal)}, true},
+		{"other func", with(func(s *shape) { s.Fn = func() {} }), nil, false},
+	}
+	for _, test := range tests {
+		if got := Equal(a, test.b, test.opts...); got != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestEqualNilEmpty(t *testing.T) {
+	type lists struct {
+		S []int
+		M map[string]int
+	}
+	a, b := lists{}, lists{S: []int{}, M: map[string]int{}}
+	if Equal(a, b) {
+		t.Error("expected nil and empty to differ")
+	}
+	if !Equal(a, b, NilEqualsEmpty()) {
+		t.Error("expected nil and empty to be equal with NilEqualsEmpty")
+	}
+}
+
+func TestEqualCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	a := &node{Name: "a"}
+	a.Next = &node{Name: "b", Next: a}
+	b := Copy(a)
+	if !Equal(a, b) {
+		t.Error("expected a copy of a cycle to be equal")
+	}
+	b.Next.Name = "c"
+	if Equal(a, b) {
+		t.Error("expected a changed cycle to differ")
+	}
+}
+
+func TestEqualSliceCycles(t *testing.T) {
+	build := func(name string) []interface{} {
+		s := make([]interface{}, 2)
+		s[0], s[1] = s, name
+		return s
+	}
+	if !Equal(build("a"), build("a")) {
+		t.Error("expected equal self-containing slices to be equal")
+	}
+	if Equal(build("a"), build("b")) {
+		t.Error("expected different self-containing slices to differ")
+	}
+}
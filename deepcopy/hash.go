@@ -0,0 +1,292 @@
+package deepcopy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// HashError is returned by Hash and HashSHA256 when v has a value that
+// cannot be hashed.
+type HashError struct {
+	Type reflect.Type // the type of the value being hashed
+	Path Path         // the location of the value within Type
+	Kind reflect.Kind // the kind of the value that cannot be hashed
+}
+
+func (e *HashError) Error() string {
+	return fmt.Sprintf("deepcopy: cannot hash %s at %s", e.Kind, e.Path.in(e.Type))
+}
+
+// Hash returns a 64-bit FNV-1a hash of v. Values without cycles that are
+// deeply equal, as reported by reflect.DeepEqual, have the same hash, so it
+// can be used to key caches on values like configs. The hash is the same
+// across runs of a program, but may change with the version of this package.
+//
+// Pointers and interfaces are followed, and all struct fields, exported or
+// not, are hashed, except for those tagged `deepcopy:"-"`. Map entries are
+// hashed in the order of their keys, and then of their values, so how a map
+// happens to be iterated doesn't matter. A pointer, map, or slice back to a
+// value that is still being hashed, a cycle, is hashed as a marker, so
+// values with cycles only have the same hash if their cycles have the same
+// shape, even if they are deeply equal. Non-nil channels, funcs, and
+// unsafe.Pointers result in a *HashError.
+func Hash(v interface{}) (uint64, error) {
+	h := fnv.New64a()
+	if err := hashValue(h, v); err != nil {
+		return 0, err
+	}
+	return h.Sum64(), nil
+}
+
+// HashSHA256 returns a SHA-256 hash of v, hashed the same way Hash hashes.
+// It is slower, but collisions are practically impossible.
+func HashSHA256(v interface{}) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	h := sha256.New()
+	if err := hashValue(h, v); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// hashValue writes v, with its type, to h.
+func hashValue(h hash.Hash, v interface{}) error {
+	original := reflect.ValueOf(v)
+	e := &encoder{w: h, stack: make(map[visit]bool)}
+	if original.IsValid() {
+		e.typ = original.Type()
+	}
+	e.writeType(original)
+	return e.encode(original)
+}
+
+// Markers for values that have no bytes of their own.
+const (
+	nilMarker   = 0
+	valueMarker = 1
+	cycleMarker = 2
+)
+
+// encoder writes the values it is given to w in a form that is the same
+// for values that are deeply equal.
+type encoder struct {
+	w     io.Writer
+	typ   reflect.Type // the type of the value being hashed
+	path  Path
+	stack map[visit]bool // the pointers, maps, and slices being encoded
+	buf   [8]byte
+}
+
+func (e *encoder) writeByte(b byte) {
+	e.buf[0] = b
+	e.w.Write(e.buf[:1])
+}
+
+func (e *encoder) writeUint(n uint64) {
+	binary.LittleEndian.PutUint64(e.buf[:], n)
+	e.w.Write(e.buf[:])
+}
+
+func (e *encoder) writeFloat(f float64) {
+	// 0 and -0 are equal, so they are encoded the same.
+	if f == 0 {
+		f = 0
+	}
+	e.writeUint(math.Float64bits(f))
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeUint(uint64(len(s)))
+	io.WriteString(e.w, s)
+}
+
+// writeType writes the type of v, which is nil if v isn't valid.
+func (e *encoder) writeType(v reflect.Value) {
+	if !v.IsValid() {
+		e.writeByte(nilMarker)
+		return
+	}
+	e.writeByte(valueMarker)
+	e.writeString(v.Type().PkgPath() + "." + v.Type().String())
+}
+
+// writeNil writes whether v, a value that can be nil, is, and reports it.
+func (e *encoder) writeNil(v reflect.Value) bool {
+	if v.IsNil() {
+		e.writeByte(nilMarker)
+		return true
+	}
+	e.writeByte(valueMarker)
+	return false
+}
+
+// push records that the pointer, map, or slice v is being encoded, reporting
+// whether it already was, in which case a cycle marker is written instead.
+func (e *encoder) push(v reflect.Value) (visit, bool) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if e.stack[key] {
+		e.writeByte(cycleMarker)
+		return key, false
+	}
+	e.stack[key] = true
+	return key, true
+}
+
+// encode writes v, which is at the current path.
+func (e *encoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if e.writeNil(v) {
+			return nil
+		}
+		key, ok := e.push(v)
+		if !ok {
+			return nil
+		}
+		err := e.encode(v.Elem())
+		delete(e.stack, key)
+		return err
+	case reflect.Interface:
+		if e.writeNil(v) {
+			return nil
+		}
+		e.writeType(v.Elem())
+		return e.encode(v.Elem())
+	case reflect.Struct:
+		// Types that have a copier have no field plans, so the fields are
+		// looked up here.
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if parseTag(f.Tag).mode == skipField {
+				continue
+			}
+			e.path = append(e.path, Step{Kind: FieldStep, Name: f.Name, Tag: f.Tag})
+			err := e.encode(v.Field(i))
+			if err != nil {
+				return err
+			}
+			e.path = e.path[:len(e.path)-1]
+		}
+	case reflect.Slice:
+		if e.writeNil(v) {
+			return nil
+		}
+		key, ok := e.push(v)
+		if !ok {
+			return nil
+		}
+		err := e.encodeElems(v)
+		delete(e.stack, key)
+		return err
+	case reflect.Array:
+		return e.encodeElems(v)
+	case reflect.Map:
+		if e.writeNil(v) {
+			return nil
+		}
+		key, ok := e.push(v)
+		if !ok {
+			return nil
+		}
+		err := e.encodeMap(v)
+		delete(e.stack, key)
+		return err
+	case reflect.Bool:
+		if v.Bool() {
+			e.writeByte(1)
+		} else {
+			e.writeByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		e.writeFloat(real(v.Complex()))
+		e.writeFloat(imag(v.Complex()))
+	case reflect.String:
+		e.writeString(v.String())
+	// What channels, funcs, and unsafe.Pointers point at has no value that
+	// can be hashed.
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if !v.IsNil() {
+			return &HashError{Type: e.typ, Path: e.path.clone(), Kind: v.Kind()}
+		}
+		e.writeByte(nilMarker)
+	}
+	return nil
+}
+
+// encodeElems writes the length and the elements of the slice or array v.
+func (e *encoder) encodeElems(v reflect.Value) error {
+	e.writeUint(uint64(v.Len()))
+	// Bytes are written as they are.
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		e.w.Write(v.Bytes())
+		return nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		e.path = append(e.path, Step{Kind: IndexStep, Index: i})
+		err := e.encode(v.Index(i))
+		if err != nil {
+			return err
+		}
+		e.path = e.path[:len(e.path)-1]
+	}
+	return nil
+}
+
+// encodeMap writes the length and the entries of the map v, in the order of
+// their encoded keys. Different keys can be encoded the same, e.g. pointers
+// to equal values, so ties are broken by the encoded values.
+func (e *encoder) encodeMap(v reflect.Value) error {
+	e.writeUint(uint64(v.Len()))
+	type entry struct {
+		key, value []byte
+	}
+	entries := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		e.path = append(e.path, Step{Kind: KeyStep, Key: iter.Key()})
+		var key, value bytes.Buffer
+		k := &encoder{w: &key, typ: e.typ, path: e.path, stack: e.stack}
+		err := k.encode(iter.Key())
+		if err != nil {
+			return err
+		}
+		k.w = &value
+		err = k.encode(iter.Value())
+		if err != nil {
+			return err
+		}
+		e.path = e.path[:len(e.path)-1]
+		entries = append(entries, entry{key.Bytes(), value.Bytes()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if c := bytes.Compare(entries[i].key, entries[j].key); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(entries[i].value, entries[j].value) < 0
+	})
+	for _, en := range entries {
+		e.w.Write(en.key)
+		e.w.Write(en.value)
+	}
+	return nil
+}
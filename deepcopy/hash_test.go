@@ -0,0 +1,160 @@
+package deepcopy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHash(t *testing.T) {
+	type config struct {
+		Name    string
+		Ports   []int
+		Labels  map[string]interface{}
+		Timeout time.Duration
+		Started time.Time
+		Parent  *config
+		Cache   map[string]string `deepcopy:"-"`
+		weight  float64
+	}
+	build := func() *config {
+		labels := make(map[string]interface{})
+		for i := 0; i < 20; i++ {
+			labels[fmt.Sprint("k", i)] = []interface{}{i, "v", map[int]bool{i: true}}
+		}
+		return &config{
+			Name:    "a",
+			Ports:   []int{80, 443},
+			Labels:  labels,
+			Timeout: time.Second,
+			Started: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			Parent:  &config{Name: "parent"},
+			weight:  0,
+		}
+	}
+	a, b := build(), build()
+	hashes := func(v interface{}) (uint64, [32]byte) {
+		h, err := Hash(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := HashSHA256(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h, sum
+	}
+	ha, sa := hashes(a)
+	hb, sb := hashes(b)
+	if ha != hb || sa != sb {
+		t.Error("expected deeply equal values to hash the same")
+	}
+
+	// The maps were built separately, so they are iterated differently, and
+	// fields tagged "-" are ignored.
+	b.Cache = map[string]string{"x": "y"}
+	b.weight = math.Copysign(0, -1)
+	if h, s := hashes(b); h != ha || s != sa {
+		t.Error("expected ignored fields and -0 not to change the hash")
+	}
+
+	changes := map[string]func(c *config){
+		"name":          func(c *config) { c.Name = "b" },
+		"port":          func(c *config) { c.Ports[1] = 8443 },
+		"nil ports":     func(c *config) { c.Ports = nil },
+		"label":         func(c *config) { c.Labels["k3"].([]interface{})[1] = "w" },
+		"label type":    func(c *config) { c.Labels["k3"] = int64(3) },
+		"nested map":    func(c *config) { c.Labels["k3"].([]interface{})[2].(map[int]bool)[3] = false },
+		"time":          func(c *config) { c.Started = c.Started.Add(time.Nanosecond) },
+		"parent":        func(c *config) { c.Parent.Name = "other" },
+		"no parent":     func(c *config) { c.Parent = nil },
+		"unexported":    func(c *config) { c.weight = 1 },
+		"moved element": func(c *config) { c.Ports = []int{80, 443, 0}[1:] },
+	}
+	for name, change := range changes {
+		c := build()
+		change(c)
+		if h, s := hashes(c); h == ha || s == sa {
+			t.Errorf("%s: expected the hash to change", name)
+		}
+	}
+}
+
+func TestHashMapTies(t *testing.T) {
+	// The keys are different, but are encoded the same, so the order of
+	// the entries is up to their values.
+	a, b := 1, 1
+	m := map[*int]string{&a: "x", &b: "y"}
+	nan := map[float64]int{math.NaN(): 1, math.NaN(): 2}
+	for _, v := range []interface{}{m, nan} {
+		first, err := Hash(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 100; i++ {
+			if h, _ := Hash(v); h != first {
+				t.Fatalf("expected %T to always hash the same", v)
+			}
+		}
+	}
+}
+
+func TestHashCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	build := func() *node {
+		n := &node{Name: "a"}
+		n.Next = &node{Name: "b", Next: n}
+		return n
+	}
+	a, b := build(), build()
+	ha, err := Hash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hb, _ := Hash(b); ha != hb {
+		t.Error("expected equal cycles to hash the same")
+	}
+	b.Next.Name = "c"
+	if hb, _ := Hash(b); ha == hb {
+		t.Error("expected changed cycles to hash differently")
+	}
+
+	slice := func(name string) []interface{} {
+		s := make([]interface{}, 2)
+		s[0], s[1] = s, name
+		return s
+	}
+	ha, err = Hash(slice("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hb, _ := Hash(slice("a")); ha != hb {
+		t.Error("expected equal slice cycles to hash the same")
+	}
+	if hb, _ := Hash(slice("b")); ha == hb {
+		t.Error("expected changed slice cycles to hash differently")
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	type handler struct {
+		Name string
+		Fn   func()
+	}
+	if _, err := Hash(handler{Name: "a"}); err != nil {
+		t.Errorf("expected a nil func to be hashed, got %s", err)
+	}
+	_, err := Hash(handler{Name: "a", Fn: func() {}})
+	var herr *HashError
+	if !errors.As(err, &herr) {
+		t.Fatalf("expected a HashError, got %v", err)
+	}
+	if expected := "deepcopy: cannot hash func at handler.Fn"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err)
+	}
+}
@@ -0,0 +1,348 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// History keeps deep copies, snapshots, of the versions of a value as it
+// changes, so that earlier versions can be looked at and returned to. Only
+// the latest versions are kept, up to the size of the history. Snapshots
+// share what is unchanged from the version before them, so a small change to
+// a large value only takes the memory of what changed.
+//
+// A History is safe for concurrent use.
+type History[T any] struct {
+	mu       sync.Mutex
+	versions []T // a ring of the versions that are kept
+	start    int // the index in versions of the oldest version
+	n        int // the number of versions that are kept
+	oldest   int // the version number of the oldest version
+}
+
+// NewHistory returns a History that keeps up to size versions. A size less
+// than 1 is taken as 1.
+func NewHistory[T any](size int) *History[T] {
+	if size < 1 {
+		size = 1
+	}
+	return &History[T]{versions: make([]T, size), oldest: 1}
+}
+
+// Commit adds a snapshot of v, copied the same way Iface copies, as the
+// latest version and returns its version number. Versions are numbered from
+// 1 in the order they are committed. Once the history is full, the oldest
+// version is dropped.
+func (h *History[T]) Commit(v T) int {
+	cpy, _ := Iface(v).(T)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n > 0 {
+		s := newSnapshotter()
+		prev, cur := reflect.ValueOf(h.at(h.n-1)).Elem(), reflect.ValueOf(&cpy).Elem()
+		s.pair(prev, cur)
+		s.share(prev, cur)
+	}
+	if h.n == len(h.versions) {
+		var zero T
+		h.versions[h.start] = zero
+		h.start = (h.start + 1) % len(h.versions)
+		h.n--
+		h.oldest++
+	}
+	*h.at(h.n) = cpy
+	h.n++
+	return h.latest()
+}
+
+// Versions returns the version numbers of the oldest and the latest version
+// that are kept. Both are 0 if nothing has been committed.
+func (h *History[T]) Versions() (oldest, latest int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.n == 0 {
+		return 0, 0
+	}
+	return h.oldest, h.latest()
+}
+
+// At returns a deep copy of the version numbered version. An error is
+// returned if the history doesn't have it.
+func (h *History[T]) At(version int) (T, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p, err := h.version(version)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return Copy(*p), nil
+}
+
+// Rollback drops the latest n versions and returns a deep copy of the
+// version that is then the latest. The versions that are dropped are gone;
+// the next Commit takes the version number after the new latest. An error
+// is returned if fewer than n+1 versions are kept.
+func (h *History[T]) Rollback(n int) (T, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var zero T
+	if n < 0 || n >= h.n {
+		return zero, fmt.Errorf("deepcopy: cannot roll back %d versions, the history has %d", n, h.n)
+	}
+	for ; n > 0; n-- {
+		*h.at(h.n - 1) = zero
+		h.n--
+	}
+	return Copy(*h.at(h.n - 1)), nil
+}
+
+// Diff returns the changes from version from to version to, as Diff does.
+// An error is returned if the history doesn't have either of them.
+func (h *History[T]) Diff(from, to int, opts ...DiffOption) ([]Change, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a, err := h.version(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := h.version(to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(*a, *b, opts...), nil
+}
+
+// at returns the ith version that is kept, starting from the oldest.
+func (h *History[T]) at(i int) *T {
+	return &h.versions[(h.start+i)%len(h.versions)]
+}
+
+// latest returns the version number of the latest version.
+func (h *History[T]) latest() int {
+	return h.oldest + h.n - 1
+}
+
+// version returns the version numbered version.
+func (h *History[T]) version(version int) (*T, error) {
+	if h.n == 0 || version < h.oldest || version > h.latest() {
+		return nil, fmt.Errorf("deepcopy: version %d is not in the history", version)
+	}
+	return h.at(version - h.oldest), nil
+}
+
+// snapshotter makes a new snapshot share what is unchanged with the one
+// before it. Snapshots are never modified, so they can share memory safely.
+type snapshotter struct {
+	// done holds what the pointers, maps, and slices of the new snapshot
+	// have been replaced with, so values that are shared within it stay
+	// shared.
+	done map[visit]reflect.Value
+	// The pointers, maps, and slices of the new snapshot are paired with
+	// those at the same places in the one before it. Only those that are
+	// always paired with the same one, which is never paired with another,
+	// can be replaced by it; otherwise what is shared within the new
+	// snapshot would change.
+	paired   map[visit]visit // what the new snapshot's are paired with
+	pairedTo map[visit]visit // the same, the other way around
+	unshared map[visit]bool  // the new snapshot's that can't be replaced
+	walked   map[[2]visit]bool
+}
+
+func newSnapshotter() *snapshotter {
+	return &snapshotter{
+		done:     make(map[visit]reflect.Value),
+		paired:   make(map[visit]visit),
+		pairedTo: make(map[visit]visit),
+		unshared: make(map[visit]bool),
+		walked:   make(map[[2]visit]bool),
+	}
+}
+
+// identify returns the visit that identifies v, a pointer, map, or slice.
+func identify(v reflect.Value) visit {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	return key
+}
+
+// pair pairs the pointers, maps, and slices within cur, the new snapshot,
+// with those at the same places in prev, the one before it, the way share
+// walks them.
+func (s *snapshotter) pair(prev, cur reflect.Value) {
+	if prev.Type() != cur.Type() {
+		return
+	}
+	switch cur.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if prev.IsNil() || cur.IsNil() {
+			return
+		}
+		p, c := identify(prev), identify(cur)
+		if q, ok := s.paired[c]; ok && q != p {
+			s.unshared[c] = true
+		}
+		if d, ok := s.pairedTo[p]; ok && d != c {
+			s.unshared[c], s.unshared[d] = true, true
+		}
+		s.paired[c], s.pairedTo[p] = p, c
+		// Types with a copier are compared as a whole.
+		if s.walked[[2]visit{p, c}] || planFor(cur.Type()).copier != nil {
+			return
+		}
+		s.walked[[2]visit{p, c}] = true
+		switch cur.Kind() {
+		case reflect.Ptr:
+			s.pair(prev.Elem(), cur.Elem())
+		case reflect.Map:
+			for iter := cur.MapRange(); iter.Next(); {
+				if v := prev.MapIndex(iter.Key()); v.IsValid() {
+					s.pair(v, iter.Value())
+				}
+			}
+		default:
+			s.pairElems(prev, cur)
+		}
+	case reflect.Interface:
+		if !prev.IsNil() && !cur.IsNil() {
+			s.pair(prev.Elem(), cur.Elem())
+		}
+	case reflect.Struct:
+		if planFor(cur.Type()).copier != nil {
+			return
+		}
+		for _, f := range planFor(cur.Type()).fields {
+			if f.exported && f.tag.mode == deepField {
+				s.pair(prev.Field(f.index), cur.Field(f.index))
+			}
+		}
+	case reflect.Array:
+		s.pairElems(prev, cur)
+	}
+}
+
+// pairElems pairs what is within the elements of the slices or arrays prev
+// and cur.
+func (s *snapshotter) pairElems(prev, cur reflect.Value) {
+	for i := 0; i < cur.Len() && i < prev.Len(); i++ {
+		s.pair(prev.Index(i), cur.Index(i))
+	}
+}
+
+// share replaces the pointers, maps, and slices within cur, the new
+// snapshot, with those at the same place in prev, the one before it, that
+// have equal values. It reports whether cur and prev are equal.
+func (s *snapshotter) share(prev, cur reflect.Value) bool {
+	if prev.Type() != cur.Type() {
+		return false
+	}
+	if planFor(cur.Type()).copier != nil {
+		equal := reflect.DeepEqual(prev.Interface(), cur.Interface())
+		switch cur.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice:
+			equal = equal && (cur.IsNil() || !s.unshared[identify(cur)])
+		}
+		return s.replace(prev, cur, equal)
+	}
+	switch cur.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if prev.IsNil() || cur.IsNil() {
+			return prev.IsNil() == cur.IsNil()
+		}
+		key := identify(cur)
+		if identify(prev) == key {
+			return true
+		}
+		if v, ok := s.done[key]; ok {
+			// Cycles are only found here while what they lead to is
+			// still being compared, so they are never shared.
+			return s.replace(prev, cur, identify(v) == identify(prev))
+		}
+		s.done[key] = cur
+		// What can't be replaced is still compared, so what is within it
+		// can be shared, but it isn't equal.
+		equal := s.shareElems(prev, cur) && !s.unshared[key]
+		if equal {
+			s.done[key] = prev
+		}
+		return s.replace(prev, cur, equal)
+	case reflect.Interface:
+		if prev.IsNil() || cur.IsNil() {
+			return prev.IsNil() == cur.IsNil()
+		}
+		if prev.Elem().Type() != cur.Elem().Type() {
+			return false
+		}
+		// What interfaces hold can't be set, so it is shared in a copy.
+		elem := reflect.New(cur.Elem().Type()).Elem()
+		elem.Set(cur.Elem())
+		equal := s.share(prev.Elem(), elem)
+		if !equal && cur.CanSet() {
+			cur.Set(elem)
+		}
+		return s.replace(prev, cur, equal)
+	case reflect.Struct:
+		equal := true
+		for _, f := range planFor(cur.Type()).fields {
+			if !f.exported {
+				continue
+			}
+			if f.tag.mode != deepField {
+				equal = reflect.DeepEqual(prev.Field(f.index).Interface(), cur.Field(f.index).Interface()) && equal
+				continue
+			}
+			equal = s.share(prev.Field(f.index), cur.Field(f.index)) && equal
+		}
+		return equal
+	case reflect.Array:
+		return s.shareElems(prev, cur)
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return prev.Pointer() == cur.Pointer()
+	}
+	return reflect.DeepEqual(prev.Interface(), cur.Interface())
+}
+
+// shareElems shares what is within the pointers, maps, slices, or arrays
+// prev and cur, reporting whether they are equal.
+func (s *snapshotter) shareElems(prev, cur reflect.Value) bool {
+	switch cur.Kind() {
+	case reflect.Ptr:
+		return s.share(prev.Elem(), cur.Elem())
+	case reflect.Map:
+		equal := prev.Len() == cur.Len()
+		for iter := cur.MapRange(); iter.Next(); {
+			p := prev.MapIndex(iter.Key())
+			if !p.IsValid() {
+				equal = false
+				continue
+			}
+			// Map values can't be set, so they are shared in a copy.
+			value := reflect.New(cur.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			if s.share(p, value) {
+				cur.SetMapIndex(iter.Key(), p)
+			} else {
+				cur.SetMapIndex(iter.Key(), value)
+				equal = false
+			}
+		}
+		return equal
+	}
+	equal := prev.Len() == cur.Len()
+	for i := 0; i < cur.Len() && i < prev.Len(); i++ {
+		equal = s.share(prev.Index(i), cur.Index(i)) && equal
+	}
+	return equal
+}
+
+// replace sets cur to prev, if it can and they are equal, and returns
+// equal.
+func (s *snapshotter) replace(prev, cur reflect.Value, equal bool) bool {
+	if equal && cur.CanSet() {
+		cur.Set(prev)
+	}
+	return equal
+}
@@ -0,0 +1,155 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	type settings struct {
+		Name    string
+		Limits  map[string]int
+		Servers []*owner
+		Extra   interface{}
+	}
+	state := &settings{
+		Name:    "a",
+		Limits:  map[string]int{"cpu": 1},
+		Servers: []*owner{{Name: "s1"}, {Name: "s2"}},
+		Extra:   &owner{Name: "x"},
+	}
+	h := NewHistory[*settings](3)
+	if oldest, latest := h.Versions(); oldest != 0 || latest != 0 {
+		t.Errorf("expected no versions, got %d to %d", oldest, latest)
+	}
+	if v := h.Commit(state); v != 1 {
+		t.Errorf("expected version 1, got %d", v)
+	}
+	state.Name = "b"
+	state.Servers[1].Name = "s2b"
+	if v := h.Commit(state); v != 2 {
+		t.Errorf("expected version 2, got %d", v)
+	}
+
+	// What didn't change is shared with the version before; what did isn't.
+	v1, v2 := *h.at(0), *h.at(1)
+	if v1 == v2 {
+		t.Error("expected the changed struct to be a new copy")
+	}
+	if v1.Servers[0] != v2.Servers[0] || reflect.ValueOf(v1.Limits).Pointer() != reflect.ValueOf(v2.Limits).Pointer() || v1.Extra != v2.Extra {
+		t.Error("expected the unchanged values to be shared")
+	}
+	if v1.Servers[1] == v2.Servers[1] || v1.Servers[1].Name != "s2" {
+		t.Errorf("expected the changed server to be a new copy, got %+v", v1.Servers[1])
+	}
+
+	changes, err := h.Diff(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, c := range changes {
+		paths = append(paths, c.Path.String())
+	}
+	if !reflect.DeepEqual(paths, []string{"Name", "Servers[1].Name"}) {
+		t.Errorf("expected changes to Name and Servers[1].Name, got %v", changes)
+	}
+
+	// At returns a copy, so changing it doesn't change the history.
+	got, err := h.At(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Servers[0].Name = "changed"
+	if got, _ := h.At(1); got.Name != "a" || got.Servers[0].Name != "s1" {
+		t.Errorf("expected version 1 to be unchanged, got %+v", got)
+	}
+
+	// The oldest versions are dropped once the history is full.
+	state.Limits["cpu"] = 2
+	h.Commit(state)
+	h.Commit(state)
+	if oldest, latest := h.Versions(); oldest != 2 || latest != 4 {
+		t.Errorf("expected versions 2 to 4, got %d to %d", oldest, latest)
+	}
+	if _, err := h.At(1); err == nil {
+		t.Error("expected an error for a dropped version")
+	}
+
+	got, err = h.Rollback(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "b" || got.Limits["cpu"] != 1 {
+		t.Errorf("expected version 2, got %+v", got)
+	}
+	if _, err := h.Rollback(1); err == nil {
+		t.Error("expected an error rolling back past the oldest version")
+	}
+	if v := h.Commit(state); v != 3 {
+		t.Errorf("expected version 3 after rolling back, got %d", v)
+	}
+}
+
+func TestHistoryCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	a := &node{Name: "a"}
+	a.Next = &node{Name: "b", Next: a}
+	h := NewHistory[*node](2)
+	h.Commit(a)
+	a.Next.Name = "c"
+	h.Commit(a)
+	got, err := h.At(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Next.Name != "c" || got.Next.Next != got {
+		t.Errorf("expected the cycle to be kept, got %+v", got)
+	}
+	got, _ = h.At(1)
+	if got.Next.Name != "b" || got.Next.Next != got {
+		t.Errorf("expected the first version to be unchanged, got %+v", got)
+	}
+}
+
+func TestHistoryAliasing(t *testing.T) {
+	type pair struct {
+		A, B *int
+	}
+	one, two, n := 1, 1, 1
+	h := NewHistory[pair](3)
+	h.Commit(pair{A: &one, B: &two})
+	h.Commit(pair{A: &n, B: &n})
+	h.Commit(pair{A: &one, B: &two})
+	for version, same := range map[int]bool{1: false, 2: true, 3: false} {
+		got, err := h.At(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got.A == got.B) != same || *got.A != 1 || *got.B != 1 {
+			t.Errorf("%d: expected A and B to be the same pointer: %t, got %v and %v", version, same, got.A, got.B)
+		}
+	}
+}
+
+func TestHistorySliceCycles(t *testing.T) {
+	s := make([]interface{}, 2)
+	s[0], s[1] = s, "a"
+	h := NewHistory[[]interface{}](2)
+	h.Commit(s)
+	s[1] = "b"
+	h.Commit(s)
+	for version, name := range map[int]string{1: "a", 2: "b"} {
+		got, err := h.At(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inner, ok := got[0].([]interface{})
+		if !ok || &inner[0] != &got[0] || got[1] != name {
+			t.Errorf("%d: expected a slice that contains itself with %s, got %v", version, name, got[1])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Hook is called for each value that is copied, before it is copied, with
+// the value's path within the value being copied. It returns the value to
+// use instead, or v to keep it, and what to do with it. The path is reused
+// while copying, so it must be cloned to be kept.
+type Hook func(path Path, v reflect.Value) (reflect.Value, Action)
+
+// Action says what is done with the value a Hook returns.
+type Action int
+
+const (
+	// Continue copies the returned value as v would have been, calling the
+	// hooks for what it contains. It must be of the same type as v; an
+	// invalid reflect.Value is the zero value.
+	Continue Action = iota
+	// Replace sets the copy to the returned value as it is, without copying
+	// it. It must be assignable to v's type; an invalid reflect.Value is the
+	// zero value.
+	Replace
+	// Omit leaves the copy as the zero value.
+	Omit
+)
+
+// runHooks calls the hooks for original, which is copied into cpy. It
+// returns the value to copy instead of original, or whether the copy has
+// been set already.
+func (c *copier) runHooks(original, cpy reflect.Value) (reflect.Value, bool, error) {
+	for _, h := range c.hooks {
+		v, action := h(c.path, original)
+		if action == Omit {
+			cpy.Set(reflect.Zero(cpy.Type()))
+			return original, true, nil
+		}
+		if !v.IsValid() {
+			v = reflect.Zero(original.Type())
+		}
+		if action == Replace {
+			if !v.Type().AssignableTo(cpy.Type()) {
+				return original, true, c.hookError(v.Type(), cpy.Type())
+			}
+			cpy.Set(v)
+			return original, true, nil
+		}
+		if v.Type() != original.Type() {
+			return original, true, c.hookError(v.Type(), original.Type())
+		}
+		original = v
+		if c.unexported {
+			original = addressable(original)
+		}
+	}
+	return original, false, nil
+}
+
+// hookError returns the error for a hook that returned a value of type got
+// where one of type want was needed.
+func (c *copier) hookError(got, want reflect.Type) error {
+	return &CopierError{Type: c.typ, Path: c.path.clone(), Err: fmt.Errorf("hook returned %s, expected %s", got, want)}
+}
+
+// isFlat reports whether values copied with p can be copied as a whole. They
+// never are when there are hooks, as the hooks are called for every value.
+func (c *copier) isFlat(p *plan) bool {
+	return len(c.hooks) == 0 && p.isFlat(c.unexported)
+}
@@ -0,0 +1,104 @@
+package deepcopy
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	type account struct {
+		User     string
+		Password string `secret:"true"`
+		Created  time.Time
+		Tags     []string
+		Notes    map[string]string
+		Internal *int
+		Cache    []byte
+	}
+	est := time.FixedZone("EST", -5*60*60)
+	n := 42
+	a := &account{
+		User:     "  joel ",
+		Password: "hunter2",
+		Created:  time.Date(2020, 1, 2, 3, 4, 5, 0, est),
+		Tags:     []string{" a", "b "},
+		Notes:    map[string]string{"k": " v "},
+		Internal: &n,
+		Cache:    []byte("cached"),
+	}
+	mask := func(path Path, v reflect.Value) (reflect.Value, Action) {
+		if len(path) > 0 && path[len(path)-1].Tag.Get("secret") == "true" {
+			return reflect.ValueOf("***"), Replace
+		}
+		return v, Continue
+	}
+	trim := func(path Path, v reflect.Value) (reflect.Value, Action) {
+		if v.Kind() == reflect.String {
+			return reflect.ValueOf(strings.TrimSpace(v.String())), Continue
+		}
+		return v, Continue
+	}
+	utc := func(path Path, v reflect.Value) (reflect.Value, Action) {
+		if tm, ok := v.Interface().(time.Time); ok {
+			return reflect.ValueOf(tm.UTC()), Continue
+		}
+		return v, Continue
+	}
+	omit := func(path Path, v reflect.Value) (reflect.Value, Action) {
+		if path.String() == "Internal" || path.String() == "Cache" {
+			return reflect.Value{}, Omit
+		}
+		return v, Continue
+	}
+	cpy := Copy(a, WithHook(mask), WithHook(trim), WithHook(utc), WithHook(omit))
+	expected := &account{
+		User:     "joel",
+		Password: "***",
+		Created:  time.Date(2020, 1, 2, 8, 4, 5, 0, time.UTC),
+		Tags:     []string{"a", "b"},
+		Notes:    map[string]string{"k": "v"},
+	}
+	if !reflect.DeepEqual(cpy, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cpy)
+	}
+	// The original is left as it was.
+	if a.User != "  joel " || a.Password != "hunter2" || a.Tags[0] != " a" || a.Notes["k"] != " v " || a.Created.Location() != est {
+		t.Errorf("the original was changed: %+v", a)
+	}
+}
+
+func TestHookErrors(t *testing.T) {
+	type record struct {
+		ID   int
+		Name string
+	}
+	tests := []struct {
+		name   string
+		action Action
+		value  reflect.Value
+		err    string
+	}{
+		{"continue", Continue, reflect.ValueOf(int64(1)), "deepcopy: cannot copy record.ID: hook returned int64, expected int"},
+		{"replace", Replace, reflect.ValueOf("1"), "deepcopy: cannot copy record.ID: hook returned string, expected int"},
+	}
+	for _, test := range tests {
+		hook := func(path Path, v reflect.Value) (reflect.Value, Action) {
+			if path.String() == "ID" {
+				return test.value, test.action
+			}
+			return v, Continue
+		}
+		_, err := IfaceE(record{ID: 1, Name: "a"}, WithHook(hook))
+		var cerr *CopierError
+		if !errors.As(err, &cerr) {
+			t.Errorf("%s: expected a CopierError, got %v", test.name, err)
+			continue
+		}
+		if err.Error() != test.err {
+			t.Errorf("%s: expected %q, got %q", test.name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// Limit is a limit on a copy that can be exceeded.
+type Limit int
+
+const (
+	// DepthLimit is set by MaxDepth.
+	DepthLimit Limit = iota + 1
+	// NodeLimit is set by MaxNodes.
+	NodeLimit
+	// LengthLimit is set by MaxLength.
+	LengthLimit
+	// ContextLimit is set by WithContext.
+	ContextLimit
+)
+
+// LimitError is returned when a copy is stopped because a limit was
+// exceeded or its context is done.
+type LimitError struct {
+	Type  reflect.Type // the type of the value being copied
+	Path  Path         // the location, within Type, where the copy stopped
+	Limit Limit        // the limit that was exceeded
+	Max   int          // the value of the limit; 0 for ContextLimit
+	Err   error        // the context's error, for ContextLimit
+}
+
+func (e *LimitError) Error() string {
+	at := e.Path.in(e.Type)
+	switch e.Limit {
+	case DepthLimit:
+		return fmt.Sprintf("deepcopy: depth exceeds %d at %s", e.Max, at)
+	case NodeLimit:
+		return fmt.Sprintf("deepcopy: more than %d values copied at %s", e.Max, at)
+	case LengthLimit:
+		return fmt.Sprintf("deepcopy: total length of slices and maps exceeds %d at %s", e.Max, at)
+	}
+	return fmt.Sprintf("deepcopy: copy stopped at %s: %s", at, e.Err)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// contextInterval is how many values are copied between checks of the
+// context.
+const contextInterval = 256
+
+// counters count what has been copied, for the limits. They are shared by
+// the workers of a parallel copy.
+type counters struct {
+	nodes  atomic.Int64 // how many values have been copied
+	length atomic.Int64 // the total length of the slices and maps copied
+}
+
+// limited reports whether cfg sets any limits.
+func (cfg *config) limited() bool {
+	return cfg.maxDepth > 0 || cfg.maxNodes > 0 || cfg.maxLength > 0 || cfg.ctx != nil
+}
+
+// checkLimits counts the value about to be copied and checks the depth,
+// node, and context limits.
+func (c *copier) checkLimits() error {
+	nodes := c.counts.nodes.Add(1)
+	switch {
+	case c.maxDepth > 0 && len(c.path) > c.maxDepth:
+		return c.limitError(DepthLimit, c.maxDepth)
+	case c.maxNodes > 0 && nodes > int64(c.maxNodes):
+		return c.limitError(NodeLimit, c.maxNodes)
+	case c.ctx != nil && nodes%contextInterval == 1:
+		if err := c.ctx.Err(); err != nil {
+			e := c.limitError(ContextLimit, 0)
+			e.Err = err
+			return e
+		}
+	}
+	return nil
+}
+
+// addLength adds n, the length of a slice or map about to be copied, to the
+// total and checks the length limit.
+func (c *copier) addLength(n int) error {
+	length := c.counts.length.Add(int64(n))
+	if c.maxLength > 0 && length > int64(c.maxLength) {
+		return c.limitError(LengthLimit, c.maxLength)
+	}
+	return nil
+}
+
+func (c *copier) limitError(l Limit, max int) *LimitError {
+	return &LimitError{Type: c.typ, Path: c.path.clone(), Limit: l, Max: max}
+}
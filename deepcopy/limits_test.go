@@ -0,0 +1,102 @@
+package deepcopy
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+		Attrs    map[string]string
+	}
+	// A tree three levels deep with two children per node.
+	var build func(depth int) *node
+	build = func(depth int) *node {
+		n := &node{Name: "n", Attrs: map[string]string{"a": "b"}}
+		if depth > 0 {
+			n.Children = []*node{build(depth - 1), build(depth - 1)}
+		}
+		return n
+	}
+	tree := build(2)
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		limit Limit
+		path  string
+	}{
+		{"no limits", nil, 0, ""},
+		{"deep enough", []Option{MaxDepth(5)}, 0, ""},
+		{"too deep", []Option{MaxDepth(4)}, DepthLimit, "Children[0].Children[0].Name"},
+		{"enough nodes", []Option{MaxNodes(100)}, 0, ""},
+		{"too many nodes", []Option{MaxNodes(10)}, NodeLimit, "Children[0].Children[0].Name"},
+		{"long enough", []Option{MaxLength(13)}, 0, ""},
+		{"too long", []Option{MaxLength(12)}, LengthLimit, "Attrs"},
+	}
+	for _, test := range tests {
+		cpy, err := IfaceE(tree, test.opts...)
+		if test.limit == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.name, err)
+			} else if changes := Diff(tree, cpy); len(changes) != 0 {
+				t.Errorf("%s: expected an equal copy, got %v", test.name, changes)
+			}
+			continue
+		}
+		var lerr *LimitError
+		if !errors.As(err, &lerr) {
+			t.Errorf("%s: expected a LimitError, got %v", test.name, err)
+			continue
+		}
+		if lerr.Limit != test.limit || lerr.Path.String() != test.path {
+			t.Errorf("%s: expected limit %d at %s, got %d at %s", test.name, test.limit, test.path, lerr.Limit, lerr.Path)
+		}
+	}
+}
+
+func TestLimitsCopy(t *testing.T) {
+	type list struct {
+		V    int
+		Next *list
+	}
+	l := list{V: 1, Next: &list{V: 2, Next: &list{V: 3}}}
+	if _, err := IfaceE(l, MaxDepth(1)); err == nil {
+		t.Fatal("expected the limit to be hit")
+	}
+	// Copy can't report that the limit was hit, so it doesn't take limits.
+	for name, opt := range map[string]Option{"limit": MaxDepth(1), "strict": OnUncopyable(Strict)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected Copy to panic", name)
+				}
+			}()
+			Copy(l, opt)
+		}()
+	}
+}
+
+func TestLimitsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	v := make([]interface{}, 1000)
+	for i := range v {
+		v[i] = []int{i}
+	}
+	_, err := IfaceE(v, WithContext(ctx))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cancel()
+	_, err = IfaceE(v, WithContext(ctx))
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != ContextLimit || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a LimitError for the canceled context, got %v", err)
+	}
+	if err.Error() != "deepcopy: copy stopped at []interface {}: context canceled" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
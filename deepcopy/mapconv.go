@@ -0,0 +1,525 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ToMap deep copies the struct v, or the struct v points to, into nested
+// generic maps: structs become map[string]interface{}, slices and arrays
+// []interface{}, and maps with string or integer keys map[string]interface{}.
+// Fields are named and omitted the way encoding/json does it, following
+// their json tags, including omitempty; the fields of embedded structs are
+// promoted, with the fields of the outer struct taking precedence.
+//
+// Other values are deep copied as they are, so numbers keep their type and
+// time.Time stays a time.Time. []byte is copied as a []byte. Channels,
+// funcs, and cyclic values result in an error.
+func ToMap(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("deepcopy: cannot convert %T to a map, expected a struct", v)
+	}
+	m := &mapper{copier: newCopier(rv.Type(), newConfig(nil)), onPath: make(map[visit]bool)}
+	out := make(map[string]interface{})
+	err := m.structToMap(out, addressable(rv))
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FromMap deep copies m into the struct v points to, the reverse of ToMap.
+// Keys are matched to fields the way encoding/json matches them, except
+// that the case must match; keys that match no field are ignored, as are
+// fields that have no key. Nested maps fill structs and maps, slices fill
+// slices and arrays, and numbers are converted to the field's type if they
+// fit, so the float64s of decoded JSON can fill integer fields.
+func FromMap(m map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("deepcopy: cannot convert a map into %T, expected a non-nil pointer to a struct", v)
+	}
+	mp := &mapper{copier: newCopier(rv.Elem().Type(), newConfig(nil))}
+	_, err := mp.mapToStruct(rv.Elem(), reflect.ValueOf(m), nil)
+	return err
+}
+
+// mapper holds the state of a single ToMap or FromMap. The copier copies
+// the values that are kept as they are; its path is the location, within
+// the struct, being converted.
+type mapper struct {
+	*copier
+	onPath map[visit]bool // the pointers, maps, and slices on the current path, for ToMap
+}
+
+// mapField is a struct field as ToMap and FromMap see it.
+type mapField struct {
+	index     int
+	name      string // the key of the field in the map
+	omitEmpty bool
+	embedded  bool // the field is an embedded struct whose fields are promoted
+}
+
+// mapFieldCache caches the mapFields of struct types.
+var mapFieldCache sync.Map
+
+// mapFields returns the fields of the struct type t, embedded structs
+// first, so the fields of t take precedence over the ones they promote.
+func mapFields(t reflect.Type) []mapField {
+	if fs, ok := mapFieldCache.Load(t); ok {
+		return fs.([]mapField)
+	}
+	var embedded, fields []mapField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// Embedded structs without a name in their tag are promoted; the
+		// exported fields of unexported ones too, as encoding/json does.
+		if f.Anonymous && ft.Kind() == reflect.Struct && name == "" {
+			embedded = append(embedded, mapField{index: i, embedded: true})
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if !hasTag || name == "" {
+			name = f.Name
+		}
+		fields = append(fields, mapField{index: i, name: name, omitEmpty: hasOpt(opts, "omitempty")})
+	}
+	fs, _ := mapFieldCache.LoadOrStore(t, append(embedded, fields...))
+	return fs.([]mapField)
+}
+
+// hasOpt reports whether the comma separated tag options opts have opt.
+func hasOpt(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether v is empty the way omitempty sees it.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
+
+// structToMap sets the fields of the struct v, which must be addressable, in
+// out.
+func (m *mapper) structToMap(out map[string]interface{}, v reflect.Value) error {
+	for _, f := range mapFields(v.Type()) {
+		fv := v.Field(f.index)
+		if !fv.CanInterface() {
+			fv = unlock(fv)
+		}
+		if f.embedded {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				key, err := m.push(fv)
+				if err != nil {
+					return err
+				}
+				err = m.structToMap(out, fv.Elem())
+				delete(m.onPath, key)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err := m.structToMap(out, fv)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if f.omitEmpty && isEmpty(fv) {
+			continue
+		}
+		sf := v.Type().Field(f.index)
+		m.path = append(m.path, Step{Kind: FieldStep, Name: sf.Name, Tag: sf.Tag})
+		g, err := m.toGeneric(fv)
+		if err != nil {
+			return err
+		}
+		m.path = m.path[:len(m.path)-1]
+		out[f.name] = g
+	}
+	return nil
+}
+
+// toGeneric returns v in the generic form ToMap makes of it.
+func (m *mapper) toGeneric(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	// Types that have a copier are kept as they are.
+	if planFor(v.Type()).copier != nil {
+		return m.copyOf(v)
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Kind() == reflect.Ptr {
+			key, err := m.push(v)
+			if err != nil {
+				return nil, err
+			}
+			defer delete(m.onPath, key)
+		}
+		return m.toGeneric(addressable(v.Elem()))
+	case reflect.Struct:
+		out := make(map[string]interface{})
+		err := m.structToMap(out, v)
+		return out, err
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return nil, nil
+			}
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return m.copyOf(v)
+			}
+			if v.Len() > 0 {
+				key, err := m.push(v)
+				if err != nil {
+					return nil, err
+				}
+				defer delete(m.onPath, key)
+			}
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			m.path = append(m.path, Step{Kind: IndexStep, Index: i})
+			g, err := m.toGeneric(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			m.path = m.path[:len(m.path)-1]
+			out[i] = g
+		}
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		key, err := m.push(v)
+		if err != nil {
+			return nil, err
+		}
+		defer delete(m.onPath, key)
+		out := make(map[string]interface{}, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			key := iter.Key()
+			m.path = append(m.path, Step{Kind: KeyStep, Key: key})
+			k, ok := mapKey(key)
+			if !ok {
+				return nil, m.convertError(key.Type(), reflect.TypeOf(""))
+			}
+			g, err := m.toGeneric(addressable(iter.Value()))
+			if err != nil {
+				return nil, err
+			}
+			m.path = m.path[:len(m.path)-1]
+			out[k] = g
+		}
+		return out, nil
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return nil, m.convertError(v.Type(), interfaceType)
+	}
+	return m.copyOf(v)
+}
+
+// push records that v, a non-nil pointer, map, or slice, is on the current
+// path. Only something that contains itself makes a cycle, which is an
+// error; the same value in two places is converted twice.
+func (m *mapper) push(v reflect.Value) (visit, error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if m.onPath[key] {
+		return key, fmt.Errorf("deepcopy: cannot convert the cycle at %s to a map", m.path.in(m.typ))
+	}
+	m.onPath[key] = true
+	return key, nil
+}
+
+// interfaceType is the type of interface{}.
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// mapKey returns the key of a generic map for the map key k, which must be
+// a string or an integer.
+func mapKey(k reflect.Value) (string, bool) {
+	switch {
+	case k.Kind() == reflect.String:
+		return k.String(), true
+	case isInt(k.Type()):
+		return strconv.FormatInt(k.Int(), 10), true
+	case isUint(k.Type()):
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+	return "", false
+}
+
+// copyOf returns a deep copy of v.
+func (m *mapper) copyOf(v reflect.Value) (interface{}, error) {
+	cpy := reflect.New(v.Type()).Elem()
+	err := m.copyRecursive(v, cpy)
+	if err != nil {
+		return nil, err
+	}
+	return cpy.Interface(), nil
+}
+
+// convertError returns a *ConvertError for the current path.
+func (m *mapper) convertError(from, to reflect.Type) error {
+	return &ConvertError{Type: m.typ, Path: m.path.clone(), From: from, To: to}
+}
+
+// mapToStruct sets the fields of the struct dst from the generic map src.
+// It reports whether any field was set. outer are the structs dst is
+// embedded in; a struct embedded in itself, through a pointer, only has its
+// fields promoted once, the way encoding/json promotes them.
+func (m *mapper) mapToStruct(dst, src reflect.Value, outer []reflect.Type) (bool, error) {
+	set := false
+	outer = append(outer, dst.Type())
+	for _, f := range mapFields(dst.Type()) {
+		fv := dst.Field(f.index)
+		if !fv.CanSet() {
+			fv = unlock(fv)
+		}
+		if f.embedded {
+			// Embedded pointers are only allocated if one of their fields is
+			// set.
+			if embeds(outer, fv.Type()) {
+				continue
+			}
+			target := fv
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					target = reflect.New(fv.Type().Elem()).Elem()
+				} else {
+					target = fv.Elem()
+				}
+			}
+			ok, err := m.mapToStruct(target, src, outer)
+			if err != nil {
+				return set, err
+			}
+			if ok && fv.Kind() == reflect.Ptr && fv.IsNil() {
+				fv.Set(target.Addr())
+			}
+			set = set || ok
+			continue
+		}
+		value := src.MapIndex(reflect.ValueOf(f.name))
+		if !value.IsValid() {
+			continue
+		}
+		sf := dst.Type().Field(f.index)
+		m.path = append(m.path, Step{Kind: FieldStep, Name: sf.Name, Tag: sf.Tag})
+		err := m.fromGeneric(fv, value)
+		if err != nil {
+			return set, err
+		}
+		m.path = m.path[:len(m.path)-1]
+		set = true
+	}
+	return set, nil
+}
+
+// embeds reports whether the struct, or pointer to a struct, t is one of
+// outer.
+func embeds(outer []reflect.Type, t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, o := range outer {
+		if o == t {
+			return true
+		}
+	}
+	return false
+}
+
+// fromGeneric sets dst, which must be settable, from src, a value in the
+// generic form ToMap makes.
+func (m *mapper) fromGeneric(dst, src reflect.Value) error {
+	if src.Kind() == reflect.Interface {
+		src = src.Elem()
+	}
+	if !src.IsValid() {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if src.Type() == dst.Type() {
+		return m.copyRecursive(src, dst)
+	}
+	switch {
+	case dst.Kind() == reflect.Interface && src.Type().Implements(dst.Type()):
+		cpy := reflect.New(src.Type()).Elem()
+		err := m.copyRecursive(src, cpy)
+		if err != nil {
+			return err
+		}
+		dst.Set(cpy)
+		return nil
+	case dst.Kind() == reflect.Ptr:
+		ptr := reflect.New(dst.Type().Elem())
+		err := m.fromGeneric(ptr.Elem(), src)
+		if err != nil {
+			return err
+		}
+		dst.Set(ptr)
+		return nil
+	case dst.Kind() == reflect.Struct && src.Kind() == reflect.Map && src.Type().Key().Kind() == reflect.String:
+		dst.Set(reflect.Zero(dst.Type()))
+		_, err := m.mapToStruct(dst, src, nil)
+		return err
+	case dst.Kind() == reflect.Slice && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array):
+		if src.Kind() == reflect.Slice && src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		return m.elemsFromGeneric(dst, src)
+	case dst.Kind() == reflect.Array && (src.Kind() == reflect.Slice || src.Kind() == reflect.Array) && src.Len() == dst.Len():
+		return m.elemsFromGeneric(dst, src)
+	case dst.Kind() == reflect.Map && src.Kind() == reflect.Map:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return m.mapFromGeneric(dst, src)
+	case isNumber(dst.Type()) && isNumber(src.Type()):
+		if cv, ok := convertNumber(src, dst.Type()); ok {
+			dst.Set(cv)
+			return nil
+		}
+	case widens(src.Type(), dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+	return m.convertError(src.Type(), dst.Type())
+}
+
+// elemsFromGeneric sets the elements of the slice or array dst from those
+// of src, which has as many.
+func (m *mapper) elemsFromGeneric(dst, src reflect.Value) error {
+	for i := 0; i < src.Len(); i++ {
+		m.path = append(m.path, Step{Kind: IndexStep, Index: i})
+		err := m.fromGeneric(dst.Index(i), src.Index(i))
+		if err != nil {
+			return err
+		}
+		m.path = m.path[:len(m.path)-1]
+	}
+	return nil
+}
+
+// mapFromGeneric sets dst to a map with the entries of src, whose string
+// keys are parsed if dst has integer keys.
+func (m *mapper) mapFromGeneric(dst, src reflect.Value) error {
+	out := reflect.MakeMapWithSize(dst.Type(), src.Len())
+	keyType := dst.Type().Key()
+	for iter := src.MapRange(); iter.Next(); {
+		m.path = append(m.path, Step{Kind: KeyStep, Key: iter.Key()})
+		key := reflect.New(keyType).Elem()
+		err := m.fromGeneric(key, iter.Key())
+		if err != nil && iter.Key().Kind() == reflect.String {
+			err = parseKey(key, iter.Key().String())
+		}
+		if err != nil {
+			return m.convertError(iter.Key().Type(), keyType)
+		}
+		value := reflect.New(dst.Type().Elem()).Elem()
+		err = m.fromGeneric(value, iter.Value())
+		if err != nil {
+			return err
+		}
+		m.path = m.path[:len(m.path)-1]
+		out.SetMapIndex(key, value)
+	}
+	dst.Set(out)
+	return nil
+}
+
+// parseKey sets the integer key from s, the way ToMap formats it.
+func parseKey(key reflect.Value, s string) error {
+	switch {
+	case isInt(key.Type()):
+		n, err := strconv.ParseInt(s, 10, key.Type().Bits())
+		if err != nil {
+			return err
+		}
+		key.SetInt(n)
+		return nil
+	case isUint(key.Type()):
+		n, err := strconv.ParseUint(s, 10, key.Type().Bits())
+		if err != nil {
+			return err
+		}
+		key.SetUint(n)
+		return nil
+	}
+	return fmt.Errorf("cannot parse a %s key", key.Type())
+}
+
+// isNumber reports whether t is an integer or floating point type.
+func isNumber(t reflect.Type) bool {
+	return isInt(t) || isUint(t) || isFloat(t)
+}
+
+// convertNumber converts the number v to the type t if it fits, without
+// losing anything, e.g. float64(3) to an int but not 3.5.
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	if widens(v.Type(), t) {
+		return v.Convert(t), true
+	}
+	if isFloat(v.Type()) && (isInt(t) || isUint(t)) {
+		// Floats that are out of range can't be converted safely.
+		f := v.Float()
+		if f != f || f < -1<<63 || f >= 1<<64 || (isInt(t) && f >= 1<<63) || (isUint(t) && f < 0) {
+			return reflect.Value{}, false
+		}
+	}
+	// Converting between signed and unsigned integers wraps around, so
+	// negative numbers survive the round trip below.
+	if (isInt(v.Type()) && isUint(t) && v.Int() < 0) || (isUint(v.Type()) && isInt(t) && v.Uint() > 1<<63-1) {
+		return reflect.Value{}, false
+	}
+	cv := v.Convert(t)
+	if !cv.Convert(v.Type()).Equal(v) {
+		return reflect.Value{}, false
+	}
+	return cv, true
+}
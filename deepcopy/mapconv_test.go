@@ -0,0 +1,209 @@
+package deepcopy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mapBase struct {
+	ID      int       `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type mapMeta struct {
+	Version int `json:"version"`
+}
+
+type mapItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price,omitempty"`
+}
+
+type mapOrder struct {
+	mapBase
+	*mapMeta
+	Customer string            `json:"customer"`
+	Note     string            `json:"note,omitempty"`
+	Items    []mapItem         `json:"items"`
+	Extra    map[string]string `json:"extra,omitempty"`
+	Counts   map[int]uint8     `json:"counts"`
+	Parent   *mapOrder         `json:"parent,omitempty"`
+	Data     []byte            `json:"data"`
+	Secret   string            `json:"-"`
+	Plain    bool
+	internal string
+}
+
+func TestToMap(t *testing.T) {
+	now := time.Now()
+	o := &mapOrder{
+		mapBase:  mapBase{ID: 1, Created: now},
+		mapMeta:  &mapMeta{Version: 2},
+		Customer: "ann",
+		Items:    []mapItem{{Name: "pen", Price: 1.5}, {Name: "free"}},
+		Counts:   map[int]uint8{3: 4},
+		Data:     []byte("x"),
+		Secret:   "s",
+		internal: "i",
+	}
+	m, err := ToMap(o)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]interface{}{
+		"id":       1,
+		"created":  now,
+		"version":  2,
+		"customer": "ann",
+		"items": []interface{}{
+			map[string]interface{}{"name": "pen", "price": 1.5},
+			map[string]interface{}{"name": "free"},
+		},
+		"counts": map[string]interface{}{"3": uint8(4)},
+		"data":   []byte("x"),
+		"Plain":  false,
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %v, got %v", expected, m)
+	}
+	if shared := Shares(o, m); len(shared) != 0 {
+		t.Errorf("expected the map to share nothing with the struct, got %v", shared)
+	}
+
+	// Cycles can't be represented.
+	o.Parent = o
+	if _, err := ToMap(o); err == nil {
+		t.Error("expected an error for a cycle")
+	}
+	self := map[string]interface{}{}
+	self["self"] = self
+	if _, err := ToMap(struct{ M map[string]interface{} }{self}); err == nil {
+		t.Error("expected an error for a map that contains itself")
+	}
+	list := make([]interface{}, 1)
+	list[0] = list
+	if _, err := ToMap(struct{ S []interface{} }{list}); err == nil {
+		t.Error("expected an error for a slice that contains itself")
+	}
+	r := &recursive{Name: "r"}
+	r.recursive = r
+	if _, err := ToMap(r); err == nil {
+		t.Error("expected an error for an embedded pointer to itself")
+	}
+	if _, err := ToMap(1); err == nil {
+		t.Error("expected an error for a non-struct")
+	}
+	if _, err := ToMap(struct{ C chan int }{make(chan int)}); err == nil {
+		t.Error("expected an error for a channel")
+	}
+}
+
+// recursive embeds a pointer to itself.
+type recursive struct {
+	*recursive
+	Name string
+}
+
+func TestFromMap(t *testing.T) {
+	now := time.Now()
+	o := mapOrder{
+		mapBase:  mapBase{ID: 1, Created: now},
+		mapMeta:  &mapMeta{Version: 2},
+		Customer: "ann",
+		Items:    []mapItem{{Name: "pen", Price: 1.5}},
+		Extra:    map[string]string{"a": "b"},
+		Counts:   map[int]uint8{3: 4},
+		Data:     []byte("x"),
+	}
+	m, err := ToMap(o)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var back mapOrder
+	err = FromMap(m, &back)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(back, o) {
+		t.Errorf("expected %+v, got %+v", o, back)
+	}
+
+	// The shape that decoding JSON makes.
+	var decoded map[string]interface{}
+	err = json.Unmarshal([]byte(`{"id": 5, "customer": "bob", "items": [{"name": "cup", "price": 2}], "counts": {"7": 1}, "unknown": true}`), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back = mapOrder{}
+	err = FromMap(decoded, &back)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := mapOrder{
+		mapBase:  mapBase{ID: 5},
+		Customer: "bob",
+		Items:    []mapItem{{Name: "cup", Price: 2}},
+		Counts:   map[int]uint8{7: 1},
+	}
+	if !reflect.DeepEqual(back, expected) {
+		t.Errorf("expected %+v, got %+v", expected, back)
+	}
+
+	tests := []map[string]interface{}{
+		{"id": 1.5},
+		{"id": "1"},
+		{"counts": map[string]interface{}{"x": 1}},
+		{"counts": map[string]interface{}{"1": -1}},
+		{"items": []interface{}{1}},
+	}
+	for i, test := range tests {
+		if err := FromMap(test, &back); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+	if err := FromMap(nil, back); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+}
+
+func TestFromMapRecursive(t *testing.T) {
+	var r recursive
+	if err := FromMap(map[string]interface{}{"Name": "r"}, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Name != "r" || r.recursive != nil {
+		t.Errorf("expected only Name to be set, got %+v", r)
+	}
+}
+
+func TestConvertNumber(t *testing.T) {
+	tests := []struct {
+		v        interface{}
+		to       interface{}
+		expected interface{}
+	}{
+		{float64(3), int(0), 3},
+		{float64(3.5), int(0), nil},
+		{float64(-1), uint(0), nil},
+		{float64(1e300), int64(0), nil},
+		{int64(300), int8(0), nil},
+		{int64(-1), uint64(0), nil},
+		{uint64(1 << 63), int64(0), nil},
+		{int64(100), uint8(0), uint8(100)},
+		{float64(0.5), float32(0), float32(0.5)},
+	}
+	for _, test := range tests {
+		cv, ok := convertNumber(reflect.ValueOf(test.v), reflect.TypeOf(test.to))
+		if !ok {
+			if test.expected != nil {
+				t.Errorf("%T(%v) to %T: expected %v, got no conversion", test.v, test.v, test.to, test.expected)
+			}
+			continue
+		}
+		if cv.Interface() != test.expected {
+			t.Errorf("%T(%v) to %T: expected %v, got %v", test.v, test.v, test.to, test.expected, cv)
+		}
+	}
+}
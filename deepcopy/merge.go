@@ -0,0 +1,291 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// MergeOption configures how values are merged by Merge.
+type MergeOption func(*merger)
+
+// MergeStrategy determines how a value in src is merged into the value in
+// dst when neither is merged element by element.
+type MergeStrategy int
+
+const (
+	// Override sets dst to a deep copy of the value in src.
+	Override MergeStrategy = iota
+	// KeepNonZero keeps the value in dst unless it is the zero value.
+	KeepNonZero
+)
+
+// SliceStrategy determines how a slice in src is merged into the slice in
+// dst.
+type SliceStrategy int
+
+const (
+	// ReplaceSlices merges slices like any other value, according to the
+	// MergeStrategy.
+	ReplaceSlices SliceStrategy = iota
+	// AppendSlices appends the elements of the slice in src to the slice in
+	// dst.
+	AppendSlices
+	// UnionSlices appends the elements of the slice in src that aren't
+	// already in the slice in dst, as compared by reflect.DeepEqual.
+	UnionSlices
+)
+
+// MergeValues sets the strategy for merging values. The default is Override.
+func MergeValues(s MergeStrategy) MergeOption {
+	return func(m *merger) {
+		m.values = s
+	}
+}
+
+// MergeSlices sets the strategy for merging slices. The default is
+// ReplaceSlices.
+func MergeSlices(s SliceStrategy) MergeOption {
+	return func(m *merger) {
+		m.slices = s
+	}
+}
+
+// ZeroIsUnset treats zero values in src as unset, so they never change dst.
+func ZeroIsUnset() MergeOption {
+	return func(m *merger) {
+		m.zeroIsUnset = true
+	}
+}
+
+// MergeCopyOptions sets the options the values from src are deep copied
+// with, as they are by IfaceE.
+func MergeCopyOptions(opts ...Option) MergeOption {
+	return func(m *merger) {
+		m.copyOpts = append(m.copyOpts, opts...)
+	}
+}
+
+// Merge deep merges src into the value dst points to. src is either of that
+// type or a pointer to it. Structs, arrays, maps, and the values pointers
+// point to are merged field by field, element by element, and entry by
+// entry; slices are merged according to the SliceStrategy; everything else,
+// including interfaces and types that have a copier, is merged according to
+// the MergeStrategy.
+//
+// Whatever is taken from src is deep copied, the same way IfaceE copies, so
+// later changes to src don't change dst. Unexported fields are only merged
+// when the CopyUnexported option is passed with MergeCopyOptions. Fields
+// tagged `deepcopy:"-"` or `deepcopy:"redact"` are left as they are in dst
+// and those tagged `deepcopy:"shallow"` are set without being copied.
+// sync.Mutex and the other sync primitives in dst are left as they are, as
+// they may be held.
+//
+// If an error is returned, dst may have been partly merged.
+func Merge(dst, src interface{}, opts ...MergeOption) error {
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return fmt.Errorf("deepcopy: cannot merge into %T, expected a non-nil pointer", dst)
+	}
+	d = d.Elem()
+	s := reflect.ValueOf(src)
+	if s.IsValid() && s.Type() == d.Addr().Type() {
+		if s.IsNil() {
+			return nil
+		}
+		s = s.Elem()
+	}
+	if !s.IsValid() || s.Type() != d.Type() {
+		return fmt.Errorf("deepcopy: cannot merge %T into %s", src, d.Type())
+	}
+	m := &merger{visited: make(map[visitPair]bool)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.copier = newCopier(d.Type(), newConfig(m.copyOpts))
+	if m.unexported {
+		s = addressable(s)
+	}
+	return m.merge(d, s)
+}
+
+// merger holds the state of a single Merge. The copier copies the values
+// taken from src; its path is the location being merged.
+type merger struct {
+	*copier
+	values      MergeStrategy
+	slices      SliceStrategy
+	zeroIsUnset bool
+	copyOpts    []Option
+	visited     map[visitPair]bool // the pointers whose values have been merged
+}
+
+// merge merges src into dst, which must be settable.
+func (m *merger) merge(dst, src reflect.Value) error {
+	if m.zeroIsUnset && src.IsZero() {
+		return nil
+	}
+	// The locks in dst may be held, so they are left as they are.
+	if locks[dst.Type()] {
+		return nil
+	}
+	// Types that have a copier are copied as a whole.
+	if planFor(dst.Type()).copier != nil {
+		return m.set(dst, src)
+	}
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() || dst.IsNil() {
+			return m.set(dst, src)
+		}
+		// Cycles stop at the pointers whose values are already merged.
+		key := visitPair{a: dst.Pointer(), b: src.Pointer(), typ: dst.Type()}
+		if m.visited[key] {
+			return nil
+		}
+		m.visited[key] = true
+		return m.merge(dst.Elem(), src.Elem())
+	case reflect.Struct:
+		return m.mergeStruct(dst, src)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			m.path = append(m.path, Step{Kind: IndexStep, Index: i})
+			err := m.merge(dst.Index(i), src.Index(i))
+			if err != nil {
+				return err
+			}
+			m.path = m.path[:len(m.path)-1]
+		}
+		return nil
+	case reflect.Slice:
+		if src.IsNil() || m.slices == ReplaceSlices {
+			return m.set(dst, src)
+		}
+		return m.mergeSlice(dst, src)
+	case reflect.Map:
+		if src.IsNil() {
+			return m.set(dst, src)
+		}
+		return m.mergeMap(dst, src)
+	}
+	return m.set(dst, src)
+}
+
+// set merges src into dst as a whole, according to the MergeStrategy.
+func (m *merger) set(dst, src reflect.Value) error {
+	if m.values == KeepNonZero && !dst.IsZero() {
+		return nil
+	}
+	cpy, err := m.copy(src)
+	if err != nil {
+		return err
+	}
+	dst.Set(cpy)
+	return nil
+}
+
+// copy returns a deep copy of src.
+func (m *merger) copy(src reflect.Value) (reflect.Value, error) {
+	cpy := reflect.New(src.Type()).Elem()
+	err := m.copyRecursive(src, cpy)
+	return cpy, err
+}
+
+// mergeStruct merges the fields of src into those of dst.
+func (m *merger) mergeStruct(dst, src reflect.Value) error {
+	for _, f := range planFor(src.Type()).fields {
+		dstField, srcField := dst.Field(f.index), src.Field(f.index)
+		if !f.exported {
+			if !m.unexported {
+				continue
+			}
+			dstField, srcField = unlock(dstField), unlock(srcField)
+		}
+		switch f.tag.mode {
+		case skipField, redactField:
+			continue
+		case shallowField:
+			if !(m.zeroIsUnset && srcField.IsZero()) && !(m.values == KeepNonZero && !dstField.IsZero()) {
+				dstField.Set(srcField)
+			}
+			continue
+		}
+		m.path = append(m.path, f.step())
+		err := m.merge(dstField, srcField)
+		if err != nil {
+			return err
+		}
+		m.path = m.path[:len(m.path)-1]
+	}
+	return nil
+}
+
+// mergeSlice appends the elements of src to dst according to the
+// SliceStrategy.
+func (m *merger) mergeSlice(dst, src reflect.Value) error {
+	out := dst
+	for i := 0; i < src.Len(); i++ {
+		elem := src.Index(i)
+		if m.slices == UnionSlices && contains(out, elem) {
+			continue
+		}
+		m.path = append(m.path, Step{Kind: IndexStep, Index: i})
+		cpy, err := m.copy(elem)
+		if err != nil {
+			return err
+		}
+		m.path = m.path[:len(m.path)-1]
+		out = reflect.Append(out, cpy)
+	}
+	dst.Set(out)
+	return nil
+}
+
+// contains reports whether the slice s has an element that is deeply equal
+// to v.
+func contains(s, v reflect.Value) bool {
+	for i := 0; i < s.Len(); i++ {
+		if reflect.DeepEqual(s.Index(i).Interface(), v.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeMap merges the entries of src into dst. Entries that are only in src
+// are added; those in both are merged.
+func (m *merger) mergeMap(dst, src reflect.Value) error {
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), src.Len()))
+	}
+	var keyPlan *plan
+	if m.mapKeys {
+		keyPlan = planFor(src.Type().Key())
+	}
+	for iter := src.MapRange(); iter.Next(); {
+		key, srcValue := iter.Key(), iter.Value()
+		if m.zeroIsUnset && srcValue.IsZero() {
+			continue
+		}
+		if m.unexported {
+			srcValue = addressable(srcValue)
+		}
+		m.path = append(m.path, Step{Kind: KeyStep, Key: key})
+		value := reflect.New(srcValue.Type()).Elem()
+		if existing := dst.MapIndex(key); existing.IsValid() {
+			value.Set(existing)
+		}
+		err := m.merge(value, srcValue)
+		if err != nil {
+			return err
+		}
+		m.path = m.path[:len(m.path)-1]
+		if keyPlan != nil && !dst.MapIndex(key).IsValid() {
+			key, err = m.copyKey(keyPlan, key)
+			if err != nil {
+				return err
+			}
+		}
+		dst.SetMapIndex(key, value)
+	}
+	return nil
+}
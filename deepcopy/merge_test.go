@@ -0,0 +1,243 @@
+package deepcopy
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mergeConfig struct {
+	Name    string
+	Port    int
+	Debug   bool
+	Timeout time.Duration
+	Started time.Time
+	Hosts   []string
+	Labels  map[string]string
+	DB      *mergeDB
+	Servers map[string]mergeDB
+	Cache   map[string]int `deepcopy:"-"`
+	secret  string
+}
+
+type mergeDB struct {
+	Host  string
+	Ports []int
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Now()
+	defaults := func() mergeConfig {
+		return mergeConfig{
+			Name:    "app",
+			Port:    80,
+			Timeout: time.Second,
+			Hosts:   []string{"a"},
+			Labels:  map[string]string{"env": "dev", "team": "x"},
+			DB:      &mergeDB{Host: "localhost", Ports: []int{5432}},
+			Servers: map[string]mergeDB{"a": {Host: "a"}},
+			Cache:   map[string]int{"x": 1},
+		}
+	}
+	file := &mergeConfig{
+		Port:    8080,
+		Started: now,
+		Hosts:   []string{"a", "b"},
+		Labels:  map[string]string{"env": "prod"},
+		DB:      &mergeDB{Ports: []int{5433}},
+		Servers: map[string]mergeDB{"a": {Ports: []int{1}}, "b": {Host: "b"}},
+		Cache:   map[string]int{"y": 2},
+		secret:  "s",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []MergeOption
+		expected mergeConfig
+	}{
+		{
+			name: "override",
+			expected: mergeConfig{
+				Port:    8080,
+				Started: now,
+				Hosts:   []string{"a", "b"},
+				Labels:  map[string]string{"env": "prod", "team": "x"},
+				DB:      &mergeDB{Ports: []int{5433}},
+				Servers: map[string]mergeDB{"a": {Ports: []int{1}}, "b": {Host: "b"}},
+				Cache:   map[string]int{"x": 1},
+			},
+		},
+		{
+			name: "zero is unset",
+			opts: []MergeOption{ZeroIsUnset()},
+			expected: mergeConfig{
+				Name:    "app",
+				Port:    8080,
+				Timeout: time.Second,
+				Started: now,
+				Hosts:   []string{"a", "b"},
+				Labels:  map[string]string{"env": "prod", "team": "x"},
+				DB:      &mergeDB{Host: "localhost", Ports: []int{5433}},
+				Servers: map[string]mergeDB{"a": {Host: "a", Ports: []int{1}}, "b": {Host: "b"}},
+				Cache:   map[string]int{"x": 1},
+			},
+		},
+		{
+			name: "keep non-zero",
+			opts: []MergeOption{MergeValues(KeepNonZero)},
+			expected: mergeConfig{
+				Name:    "app",
+				Port:    80,
+				Timeout: time.Second,
+				Started: now,
+				Hosts:   []string{"a"},
+				Labels:  map[string]string{"env": "dev", "team": "x"},
+				DB:      &mergeDB{Host: "localhost", Ports: []int{5432}},
+				Servers: map[string]mergeDB{"a": {Host: "a", Ports: []int{1}}, "b": {Host: "b"}},
+				Cache:   map[string]int{"x": 1},
+			},
+		},
+		{
+			name: "append slices",
+			opts: []MergeOption{ZeroIsUnset(), MergeSlices(AppendSlices)},
+			expected: mergeConfig{
+				Name:    "app",
+				Port:    8080,
+				Timeout: time.Second,
+				Started: now,
+				Hosts:   []string{"a", "a", "b"},
+				Labels:  map[string]string{"env": "prod", "team": "x"},
+				DB:      &mergeDB{Host: "localhost", Ports: []int{5432, 5433}},
+				Servers: map[string]mergeDB{"a": {Host: "a", Ports: []int{1}}, "b": {Host: "b"}},
+				Cache:   map[string]int{"x": 1},
+			},
+		},
+		{
+			name: "union slices",
+			opts: []MergeOption{ZeroIsUnset(), MergeSlices(UnionSlices)},
+			expected: mergeConfig{
+				Name:    "app",
+				Port:    8080,
+				Timeout: time.Second,
+				Started: now,
+				Hosts:   []string{"a", "b"},
+				Labels:  map[string]string{"env": "prod", "team": "x"},
+				DB:      &mergeDB{Host: "localhost", Ports: []int{5432, 5433}},
+				Servers: map[string]mergeDB{"a": {Host: "a", Ports: []int{1}}, "b": {Host: "b"}},
+				Cache:   map[string]int{"x": 1},
+			},
+		},
+		{
+			name: "unexported",
+			opts: []MergeOption{ZeroIsUnset(), MergeCopyOptions(CopyUnexported())},
+			expected: mergeConfig{
+				Name:    "app",
+				Port:    8080,
+				Timeout: time.Second,
+				Started: now,
+				Hosts:   []string{"a", "b"},
+				Labels:  map[string]string{"env": "prod", "team": "x"},
+				DB:      &mergeDB{Host: "localhost", Ports: []int{5433}},
+				Servers: map[string]mergeDB{"a": {Host: "a", Ports: []int{1}}, "b": {Host: "b"}},
+				Cache:   map[string]int{"x": 1},
+				secret:  "s",
+			},
+		},
+	}
+	for _, test := range tests {
+		dst := defaults()
+		err := Merge(&dst, file, test.opts...)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(dst, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, dst)
+		}
+		if shared := Shares(file, dst); len(shared) != 0 {
+			t.Errorf("%s: expected the result to share nothing with src, got %v", test.name, shared)
+		}
+	}
+}
+
+func TestMergeErrors(t *testing.T) {
+	var cfg mergeConfig
+	tests := []struct {
+		dst, src interface{}
+	}{
+		{cfg, cfg},
+		{(*mergeConfig)(nil), cfg},
+		{&cfg, 1},
+		{&cfg, nil},
+	}
+	for i, test := range tests {
+		if err := Merge(test.dst, test.src); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+	if err := Merge(&cfg, (*mergeConfig)(nil)); err != nil {
+		t.Errorf("expected merging a nil pointer to do nothing, got %s", err)
+	}
+
+	type hooks struct {
+		Funcs map[string]func()
+	}
+	h := hooks{}
+	err := Merge(&h, hooks{Funcs: map[string]func(){"a": func() {}}})
+	uerr, ok := err.(*UncopyableError)
+	if !ok || uerr.Path.String() != `Funcs["a"]` {
+		t.Errorf("expected an UncopyableError at Funcs[\"a\"], got %v", err)
+	}
+	err = Merge(&h, hooks{Funcs: map[string]func(){"a": func() {}}}, MergeCopyOptions(OnUncopyable(Share)))
+	if err != nil || h.Funcs["a"] == nil {
+		t.Errorf("expected the func to be shared, got %v", err)
+	}
+}
+
+func TestMergeCycles(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	dst := &node{Name: "a"}
+	dst.Next = dst
+	src := &node{Name: "b"}
+	src.Next = src
+	if err := Merge(dst, src); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dst.Name != "b" || dst.Next != dst {
+		t.Errorf("expected the cycle to be merged in place, got %+v", dst)
+	}
+}
+
+func TestMergeLocks(t *testing.T) {
+	type config struct {
+		sync.Mutex
+		Name string
+	}
+	dst := &config{Name: "a"}
+	dst.Lock()
+	if err := Merge(dst, config{Name: "b"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dst.Name != "b" {
+		t.Errorf("expected Name to be merged, got %q", dst.Name)
+	}
+	if dst.TryLock() {
+		t.Fatal("expected the held lock to be left as it is")
+	}
+	dst.Unlock()
+}
+
+func TestMergeMapZeroIsUnset(t *testing.T) {
+	dst := map[string]int{"a": 1, "c": 3}
+	if err := Merge(&dst, map[string]int{"a": 0, "b": 0, "c": 4}, ZeroIsUnset()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]int{"a": 1, "c": 4}
+	if !reflect.DeepEqual(dst, expected) {
+		t.Errorf("expected %v, got %v", expected, dst)
+	}
+}
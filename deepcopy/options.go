@@ -0,0 +1,228 @@
+package deepcopy
+
+import (
+	"context"
+	"reflect"
+)
+
+// Option configures how a value is copied.
+type Option func(*config)
+
+// config holds the settings for a single copy.
+type config struct {
+	uncopyable  Policy
+	chans       ChanPolicy
+	funcs       FuncPolicy
+	unexported  bool
+	placeholder string
+	nils        NilPolicy
+	mapKeys     bool
+	maxDepth    int
+	maxNodes    int
+	maxLength   int
+	ctx         context.Context
+	workers     int
+	threshold   int
+	hooks       []Hook
+	trace       *tracer
+}
+
+// newConfig returns the config that results from applying opts to the
+// defaults.
+func newConfig(opts []Option) config {
+	cfg := config{uncopyable: Strict}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// Policy determines what happens to values that cannot be deep copied:
+// channels, funcs, and unsafe.Pointers. Nil values are always copied as nil.
+type Policy int
+
+const (
+	// Strict stops the copy and returns an *UncopyableError.
+	Strict Policy = iota
+	// Skip leaves the value in the copy as its zero value.
+	Skip
+	// Share sets the value in the copy to the original value, so the copy
+	// and the original share it.
+	Share
+)
+
+// OnUncopyable sets the policy for values that cannot be deep copied. The
+// default is Strict.
+func OnUncopyable(p Policy) Option {
+	return func(cfg *config) {
+		cfg.uncopyable = p
+	}
+}
+
+// ChanPolicy determines how channels are copied.
+type ChanPolicy int
+
+const (
+	// UncopyableChans copies channels according to the policy set by
+	// OnUncopyable.
+	UncopyableChans ChanPolicy = iota
+	// ShareChans sets the channel in the copy to the original channel.
+	ShareChans
+	// NewChans sets the channel in the copy to a new, empty channel with the
+	// same capacity. A channel found more than once in the original is one
+	// new channel in the copy.
+	NewChans
+	// NilChans leaves the channel in the copy nil.
+	NilChans
+)
+
+// OnChan sets the policy for channels, overriding the one set by
+// OnUncopyable. The default is UncopyableChans.
+func OnChan(p ChanPolicy) Option {
+	return func(cfg *config) {
+		cfg.chans = p
+	}
+}
+
+// FuncPolicy determines how funcs are copied.
+type FuncPolicy int
+
+const (
+	// UncopyableFuncs copies funcs according to the policy set by
+	// OnUncopyable.
+	UncopyableFuncs FuncPolicy = iota
+	// ShareFuncs sets the func in the copy to the original func.
+	ShareFuncs
+	// NilFuncs leaves the func in the copy nil.
+	NilFuncs
+)
+
+// OnFunc sets the policy for funcs, overriding the one set by OnUncopyable.
+// The default is UncopyableFuncs.
+func OnFunc(p FuncPolicy) Option {
+	return func(cfg *config) {
+		cfg.funcs = p
+	}
+}
+
+// CopyUnexported copies unexported struct fields too. By default they are
+// left as their zero value.
+func CopyUnexported() Option {
+	return func(cfg *config) {
+		cfg.unexported = true
+	}
+}
+
+// RedactPlaceholder sets the value of string fields tagged with
+// `deepcopy:"redact"` in the copy. By default they are empty.
+func RedactPlaceholder(s string) Option {
+	return func(cfg *config) {
+		cfg.placeholder = s
+	}
+}
+
+// NilPolicy determines how nil and empty slices and maps are copied.
+type NilPolicy int
+
+const (
+	// PreserveNil copies nil as nil and empty as empty.
+	PreserveNil NilPolicy = iota
+	// NilToEmpty copies nil as empty, so the copy never has nil slices or
+	// maps.
+	NilToEmpty
+	// EmptyToNil copies empty as nil, so the copy never has empty slices or
+	// maps.
+	EmptyToNil
+)
+
+// OnNil sets the policy for nil and empty slices and maps. The default is
+// PreserveNil.
+func OnNil(p NilPolicy) Option {
+	return func(cfg *config) {
+		cfg.nils = p
+	}
+}
+
+// CopyMapKeys deep copies map keys too, e.g. those that are pointers or
+// interfaces holding them. By default, the copy uses the original keys.
+func CopyMapKeys() Option {
+	return func(cfg *config) {
+		cfg.mapKeys = true
+	}
+}
+
+// MaxDepth stops the copy with a *LimitError when a value is nested more
+// than n fields, elements, or map entries deep.
+func MaxDepth(n int) Option {
+	return func(cfg *config) {
+		cfg.maxDepth = n
+	}
+}
+
+// MaxNodes stops the copy with a *LimitError when more than n values have
+// been copied. Every value that is copied counts, e.g. a struct, each of its
+// fields, and what they point to, except that slices of values without
+// pointers are copied, and counted, as one.
+func MaxNodes(n int) Option {
+	return func(cfg *config) {
+		cfg.maxNodes = n
+	}
+}
+
+// MaxLength stops the copy with a *LimitError when the total length of the
+// slices and maps that have been copied exceeds n.
+func MaxLength(n int) Option {
+	return func(cfg *config) {
+		cfg.maxLength = n
+	}
+}
+
+// WithContext stops the copy with a *LimitError when ctx is done. The
+// context is checked periodically while copying.
+func WithContext(ctx context.Context) Option {
+	return func(cfg *config) {
+		cfg.ctx = ctx
+	}
+}
+
+// defaultThreshold is the length from which slices and maps are copied in
+// parallel when Parallel is given no threshold.
+const defaultThreshold = 1024
+
+// Parallel copies the elements of slices and the entries of maps that have
+// at least threshold of them, 1024 if threshold isn't positive, using up to
+// workers goroutines for the whole copy. The copy is the same as the one
+// made sequentially, including which of its values are shared. It pays off
+// for large values whose elements are expensive to copy, e.g. a []*Record;
+// slices of values without pointers are copied in bulk either way.
+func Parallel(workers, threshold int) Option {
+	return func(cfg *config) {
+		if threshold <= 0 {
+			threshold = defaultThreshold
+		}
+		cfg.workers, cfg.threshold = workers, threshold
+	}
+}
+
+// WithHook calls h for every value that is copied, from the value being
+// copied down, including the values held in interfaces, which are passed
+// again at the same path. Hooks run in the order they are given, each being
+// passed the value returned by the one before. They aren't called for struct
+// fields whose tag says how they are copied, and they are only called for
+// map keys when CopyMapKeys has them deep copied. With Parallel, hooks may
+// be called concurrently.
+func WithHook(h Hook) Option {
+	return func(cfg *config) {
+		cfg.hooks = append(cfg.hooks, h)
+	}
+}
+
+// WithStats records what is copied in s, e.g. to find out what makes a copy
+// expensive. s is reset when the copy starts. Values are counted the same
+// way MaxNodes counts them.
+func WithStats(s *Stats) Option {
+	return func(cfg *config) {
+		*s = Stats{Allocs: make(map[reflect.Kind]int)}
+		cfg.trace = &tracer{stats: s}
+	}
+}
@@ -0,0 +1,172 @@
+package deepcopy
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// parallel is the state shared by the workers of a parallel copy. Once a
+// copy goes parallel, what has been copied is tracked here, under a lock,
+// so that values shared in the original are shared in the copy no matter
+// which worker gets to them first.
+type parallel struct {
+	tokens  chan struct{} // one for each worker that can be running
+	failed  atomic.Bool   // whether a worker has failed, so the rest stop
+	mu      sync.Mutex
+	visited map[visit]reflect.Value
+	err     error       // the first error
+	panic   interface{} // the first panic
+}
+
+func (p *parallel) lookup(key visit) (reflect.Value, bool) {
+	p.mu.Lock()
+	v, ok := p.visited[key]
+	p.mu.Unlock()
+	return v, ok
+}
+
+func (p *parallel) remember(key visit, v reflect.Value) (reflect.Value, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if existing, ok := p.visited[key]; ok {
+		return existing, true
+	}
+	p.visited[key] = v
+	return v, false
+}
+
+// fail records the error or panic of a worker and stops the rest.
+func (p *parallel) fail(err error, panicked interface{}) {
+	p.mu.Lock()
+	if p.err == nil && p.panic == nil {
+		p.err, p.panic = err, panicked
+	}
+	p.mu.Unlock()
+	p.failed.Store(true)
+}
+
+// goParallel moves what c has copied to the shared state, if it isn't
+// copying in parallel already.
+func (c *copier) goParallel() {
+	if c.par != nil {
+		return
+	}
+	p := &parallel{tokens: make(chan struct{}, c.workers-1), visited: c.visited}
+	if p.visited == nil {
+		p.visited = make(map[visit]reflect.Value, 2*maxVisits)
+	}
+	for _, v := range c.visits {
+		p.visited[v.visit] = v.cpy
+	}
+	c.visits, c.visited = nil, nil
+	c.par = p
+}
+
+// worker returns a copier for a worker that continues from where c is.
+func (c *copier) worker() *copier {
+	w := newCopier(c.typ, c.config)
+	w.path = append(w.path, c.path...)
+	w.counts = c.counts
+	w.par = c.par
+	return w
+}
+
+// copyParallel calls fn for 0 through n-1 with c and with copiers for as
+// many workers as can be started. The workers take turns taking chunks of
+// what is left, so a slow chunk doesn't hold the others up.
+func (c *copier) copyParallel(n int, fn func(w *copier, i int) error) error {
+	c.goParallel()
+	p := c.par
+	chunk := n / (4 * c.workers)
+	if chunk < 1 {
+		chunk = 1
+	}
+	var next atomic.Int64
+	run := func(w *copier) {
+		for !p.failed.Load() {
+			start := int(next.Add(int64(chunk))) - chunk
+			if start >= n {
+				return
+			}
+			end := start + chunk
+			if end > n {
+				end = n
+			}
+			for i := start; i < end && !p.failed.Load(); i++ {
+				if err := fn(w, i); err != nil {
+					p.fail(err, nil)
+					return
+				}
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	worker := func(w *copier) {
+		defer wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				p.fail(nil, r)
+			}
+		}()
+		run(w)
+	}
+	// Workers are only started while tokens are free; the rest of the work
+	// is done here.
+	var workers []*copier
+start:
+	for k := 1; k < c.workers && k*chunk < n; k++ {
+		select {
+		case p.tokens <- struct{}{}:
+		default:
+			break start
+		}
+		w := c.worker()
+		workers = append(workers, w)
+		wg.Add(1)
+		go func() {
+			defer func() { <-p.tokens }()
+			worker(w)
+		}()
+	}
+	wg.Add(1)
+	worker(c)
+	wg.Wait()
+	// What the workers copied is part of what c is copying.
+	for _, w := range workers {
+		c.traced.nodes += w.traced.nodes
+		c.traced.bytes += w.traced.bytes
+	}
+	if !p.failed.Load() {
+		return nil
+	}
+	p.mu.Lock()
+	err, panicked := p.err, p.panic
+	p.mu.Unlock()
+	if panicked != nil {
+		panic(panicked)
+	}
+	return err
+}
+
+// copyMapParallel copies the entries of the map original into cpy in
+// parallel. The entries are copied by the workers and set in cpy here.
+func (c *copier) copyMapParallel(elem, keyPlan *plan, original, cpy reflect.Value) error {
+	n := original.Len()
+	keys, values := make([]reflect.Value, 0, n), make([]reflect.Value, 0, n)
+	for iter := original.MapRange(); iter.Next(); {
+		keys, values = append(keys, iter.Key()), append(values, iter.Value())
+	}
+	err := c.copyParallel(len(keys), func(w *copier, i int) error {
+		var err error
+		keys[i], values[i], err = w.copyEntry(elem, keyPlan, keys[i], values[i])
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for i := range keys {
+		cpy.SetMapIndex(keys[i], values[i])
+	}
+	return nil
+}
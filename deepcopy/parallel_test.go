@@ -0,0 +1,136 @@
+package deepcopy
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type record struct {
+	ID     int
+	Name   string
+	Tags   []string
+	Attrs  map[string]int
+	Owner  *owner
+	Next   *record
+	Scores []float64
+}
+
+type owner struct {
+	Name string
+}
+
+// makeRecords returns n records where every tenth has the same owner and
+// each points to the one after it, the last one to the first.
+func makeRecords(n int) []*record {
+	shared := &owner{Name: "shared"}
+	recs := make([]*record, n)
+	for i := range recs {
+		recs[i] = &record{
+			ID:     i,
+			Name:   fmt.Sprint("record", i),
+			Tags:   []string{"a", "b"},
+			Attrs:  map[string]int{"x": i},
+			Owner:  &owner{Name: fmt.Sprint("owner", i)},
+			Scores: []float64{1, 2, 3},
+		}
+		if i%10 == 0 {
+			recs[i].Owner = shared
+		}
+	}
+	for i, r := range recs {
+		r.Next = recs[(i+1)%n]
+	}
+	return recs
+}
+
+func TestParallel(t *testing.T) {
+	recs := makeRecords(1000)
+	cpy := Copy(recs, Parallel(4, 16))
+	if changes := Diff(recs, cpy); len(changes) != 0 {
+		t.Fatalf("expected an equal copy, got %d changes, e.g. %v", len(changes), changes[0])
+	}
+	if shared := Shares(recs, cpy); len(shared) != 0 {
+		t.Fatalf("expected the copy to share nothing, got %v", shared)
+	}
+	for i, r := range cpy {
+		if r.Next != cpy[(i+1)%len(cpy)] {
+			t.Fatalf("%d: expected Next to point at the copy of the next record", i)
+		}
+		if i%10 == 0 && r.Owner != cpy[0].Owner {
+			t.Fatalf("%d: expected the shared owner to be shared in the copy", i)
+		}
+	}
+
+	m := make(map[int]*record, len(recs))
+	for _, r := range recs {
+		m[r.ID] = r
+	}
+	mcpy := Copy(m, Parallel(4, 16), CopyMapKeys())
+	if changes := Diff(m, mcpy); len(changes) != 0 {
+		t.Fatalf("expected an equal map copy, got %d changes, e.g. %v", len(changes), changes[0])
+	}
+	for id, r := range mcpy {
+		if r.Next != mcpy[(id+1)%len(mcpy)] {
+			t.Fatalf("%d: expected Next to point at the copy of the next record", id)
+		}
+	}
+}
+
+func TestParallelErrors(t *testing.T) {
+	type job struct {
+		Run func()
+	}
+	jobs := make([]job, 100)
+	jobs[57].Run = func() {}
+	_, err := IfaceE(jobs, Parallel(4, 10))
+	var uerr *UncopyableError
+	if !errors.As(err, &uerr) || uerr.Path.String() != "[57].Run" {
+		t.Errorf("expected an UncopyableError at [57].Run, got %v", err)
+	}
+	_, err = IfaceE(makeRecords(100), Parallel(4, 10), MaxNodes(500))
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != NodeLimit {
+		t.Errorf("expected a LimitError, got %v", err)
+	}
+
+	type boom struct{ N int }
+	RegisterCopier(reflect.TypeOf(boom{}), func(src reflect.Value) (reflect.Value, error) {
+		if src.Interface().(boom).N == 42 {
+			panic("boom")
+		}
+		return src, nil
+	})
+	defer RegisterCopier(reflect.TypeOf(boom{}), nil)
+	booms := make([]boom, 100)
+	booms[42].N = 42
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("expected the panic to reach the caller, got %v", r)
+		}
+	}()
+	IfaceE(booms, Parallel(4, 10))
+}
+
+// BenchmarkRecords compares sequential and parallel copies of records. The
+// parallel copy only pays off with GOMAXPROCS above 1 and slices well past
+// the threshold; below it the two are the same, and on a single CPU the
+// parallel copy costs the locking of the shared visited table.
+func BenchmarkRecords(b *testing.B) {
+	for _, n := range []int{100, 10000, 100000} {
+		recs := makeRecords(n)
+		b.Run(fmt.Sprintf("n=%d/sequential", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Copy(recs)
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/parallel", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Copy(recs, Parallel(8, 0))
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package deepcopy
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// StepKind is the kind of a Step in a Path.
+type StepKind int
+
+const (
+	// FieldStep is a step into a struct field.
+	FieldStep StepKind = iota
+	// IndexStep is a step into an element of a slice or an array.
+	IndexStep
+	// KeyStep is a step into the value of a map entry.
+	KeyStep
+)
+
+// Step is a single step in a Path. Only the fields that correspond to the
+// step's Kind are set.
+type Step struct {
+	Kind  StepKind
+	Name  string            // the field name of a FieldStep
+	Tag   reflect.StructTag // the field tag of a FieldStep
+	Index int               // the index of an IndexStep
+	Key   reflect.Value     // the map key of a KeyStep
+}
+
+// Path is the location of a value within the value being walked, starting
+// from the top. Pointers and interfaces are followed without adding a step.
+type Path []Step
+
+// String returns the path in Go syntax, e.g. Servers[1].Ports["http"]. The
+// empty path is the value itself and is returned as an empty string.
+func (p Path) String() string {
+	var buf bytes.Buffer
+	for i, s := range p {
+		switch s.Kind {
+		case FieldStep:
+			if i > 0 {
+				buf.WriteByte('.')
+			}
+			buf.WriteString(s.Name)
+		case IndexStep:
+			buf.WriteByte('[')
+			buf.WriteString(strconv.Itoa(s.Index))
+			buf.WriteByte(']')
+		case KeyStep:
+			buf.WriteByte('[')
+			buf.WriteString(formatKey(s.Key))
+			buf.WriteByte(']')
+		}
+	}
+	return buf.String()
+}
+
+// formatKey returns the map key as it would be written in Go: strings are
+// quoted, everything else uses its default format.
+func formatKey(k reflect.Value) string {
+	if !k.IsValid() {
+		return "<nil>"
+	}
+	if k.Kind() == reflect.Interface {
+		k = k.Elem()
+		if !k.IsValid() {
+			return "<nil>"
+		}
+	}
+	if k.Kind() == reflect.String {
+		return strconv.Quote(k.String())
+	}
+	return fmt.Sprint(k)
+}
+
+// in returns the path prefixed with the name of the type it starts from,
+// e.g. Config.Hooks[2]. Pointer types are dereferenced for the name.
+func (p Path) in(t reflect.Type) string {
+	if t == nil {
+		return p.String()
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := t.Name()
+	if name == "" {
+		name = t.String()
+	}
+	if len(p) > 0 && p[0].Kind == FieldStep {
+		return name + "." + p.String()
+	}
+	return name + p.String()
+}
+
+// clone returns a copy of the path that does not share the backing array of
+// the path being built up during a walk.
+func (p Path) clone() Path {
+	if p == nil {
+		return nil
+	}
+	return append(Path(nil), p...)
+}
+
+// pattern is a parsed path pattern, e.g. Servers[*].Ports["http"]. Each
+// element is a field name or a bracketed index or key, with "[*]" matching
+// any index or key.
+type pattern []string
+
+// anyIndex is the pattern element that matches any index or key.
+const anyIndex = "[*]"
+
+// compilePattern parses s, which is written the same way Path.String
+// writes paths. A leading "." is allowed.
+func compilePattern(s string) pattern {
+	var pt pattern
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+		case '[':
+			end := closingBracket(s)
+			pt = append(pt, s[:end])
+			s = s[end:]
+		default:
+			end := len(s)
+			if i := strings.IndexAny(s, ".["); i >= 0 {
+				end = i
+			}
+			pt = append(pt, s[:end])
+			s = s[end:]
+		}
+	}
+	return pt
+}
+
+// closingBracket returns the index just past the "]" that closes the "["
+// s starts with, skipping over brackets within quoted keys. If there isn't
+// one, the rest of s is the element.
+func closingBracket(s string) int {
+	inQuote := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case inQuote && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[i] == ']':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// element returns s as it is written in a pattern.
+func (s Step) element() string {
+	switch s.Kind {
+	case IndexStep:
+		return "[" + strconv.Itoa(s.Index) + "]"
+	case KeyStep:
+		return "[" + formatKey(s.Key) + "]"
+	}
+	return s.Name
+}
+
+// matchElem reports whether the pattern element e matches s.
+func matchElem(e string, s Step) bool {
+	if e == anyIndex {
+		return s.Kind != FieldStep
+	}
+	return e == s.element()
+}
+
+// match reports whether p matches the pattern exactly.
+func (pt pattern) match(p Path) bool {
+	if len(pt) != len(p) {
+		return false
+	}
+	return pt.matchPrefix(p)
+}
+
+// matchPrefix reports whether p matches the start of the pattern.
+func (pt pattern) matchPrefix(p Path) bool {
+	if len(p) > len(pt) {
+		return false
+	}
+	for i, s := range p {
+		if !matchElem(pt[i], s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathString(t *testing.T) {
+	type Config struct{}
+	tests := []struct {
+		path     Path
+		expected string
+		in       string
+	}{
+		{nil, "", "Config"},
+		{Path{{Kind: FieldStep, Name: "Hooks"}, {Kind: IndexStep, Index: 2}}, "Hooks[2]", "Config.Hooks[2]"},
+		{Path{{Kind: IndexStep, Index: 0}, {Kind: FieldStep, Name: "Name"}}, "[0].Name", "Config[0].Name"},
+		{
+			Path{
+				{Kind: FieldStep, Name: "Servers"},
+				{Kind: IndexStep, Index: 1},
+				{Kind: FieldStep, Name: "Ports"},
+				{Kind: KeyStep, Key: reflect.ValueOf("http")},
+			},
+			`Servers[1].Ports["http"]`,
+			`Config.Servers[1].Ports["http"]`,
+		},
+		{Path{{Kind: KeyStep, Key: reflect.ValueOf(42)}}, "[42]", "Config[42]"},
+	}
+	for i, test := range tests {
+		if s := test.path.String(); s != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, s)
+		}
+		if s := test.path.in(reflect.TypeOf(&Config{})); s != test.in {
+			t.Errorf("%d: expected %q, got %q", i, test.in, s)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	servers := Path{
+		{Kind: FieldStep, Name: "Servers"},
+		{Kind: IndexStep, Index: 1},
+		{Kind: FieldStep, Name: "Ports"},
+		{Kind: KeyStep, Key: reflect.ValueOf("http")},
+	}
+	tests := []struct {
+		pattern  string
+		elems    int
+		match    bool
+		prefix   bool
+		prefixOf Path
+	}{
+		{`Servers[1].Ports["http"]`, 4, true, true, servers[:2]},
+		{`.Servers[*].Ports[*]`, 4, true, true, servers[:3]},
+		{`Servers[*].Ports`, 3, false, true, servers[:3]},
+		{`Servers[0].Ports["http"]`, 4, false, false, servers[:2]},
+		{`Servers[1].Ports["ht.[tp]"]`, 4, false, true, servers[:3]},
+		{`Servers.Ports`, 2, false, false, servers[:2]},
+	}
+	for i, test := range tests {
+		pt := compilePattern(test.pattern)
+		if len(pt) != test.elems {
+			t.Errorf("%d: expected %d elements, got %d: %q", i, test.elems, len(pt), pt)
+			continue
+		}
+		if pt.match(servers) != test.match {
+			t.Errorf("%d: expected match to be %t", i, test.match)
+		}
+		if pt.matchPrefix(test.prefixOf) != test.prefix {
+			t.Errorf("%d: expected matchPrefix to be %t", i, test.prefix)
+		}
+	}
+}
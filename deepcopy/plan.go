@@ -0,0 +1,124 @@
+package deepcopy
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// plan is what is known about copying values of a type. Working it out
+// takes a walk over the type's fields and lookups in the copier registry, so
+// it is done once per type and cached.
+type plan struct {
+	// copier copies values of the type when the type has a registered or
+	// builtin copier or implements DeepCopier.
+	copier CopierFunc
+	// flat is true when a value of the type contains nothing that needs to
+	// be deep copied, so it can be copied as a whole. flatUnexported is the
+	// same, but applies when unexported fields are copied too.
+	flat           bool
+	flatUnexported bool
+	// elem is the plan of the element type of pointers, slices, and maps.
+	// Those types can refer to themselves, so it is only looked up once it
+	// is first needed; use elemPlan to get it.
+	elem atomic.Pointer[plan]
+	// fields are the fields of structs.
+	fields []fieldPlan
+}
+
+// fieldPlan is the plan for copying a struct field.
+type fieldPlan struct {
+	index     int
+	name      string
+	exported  bool
+	tag       fieldTag
+	structTag reflect.StructTag // the field's whole tag
+	plan      *plan             // the plan for the field's type
+}
+
+// step returns the step into the field.
+func (f *fieldPlan) step() Step {
+	return Step{Kind: FieldStep, Name: f.name, Tag: f.structTag}
+}
+
+// isFlat reports whether values of the type can be copied as a whole.
+func (p *plan) isFlat(unexported bool) bool {
+	if unexported {
+		return p.flatUnexported
+	}
+	return p.flat
+}
+
+// elemPlan returns the plan for the element type of t, which must be the
+// type p is the plan for.
+func (p *plan) elemPlan(t reflect.Type) *plan {
+	if e := p.elem.Load(); e != nil {
+		return e
+	}
+	e := planFor(t.Elem())
+	p.elem.Store(e)
+	return e
+}
+
+// plans is the cache of compiled plans, keyed by reflect.Type.
+var plans sync.Map
+
+// planFor returns the plan for t, compiling it if it isn't cached.
+func planFor(t reflect.Type) *plan {
+	if p, ok := plans.Load(t); ok {
+		return p.(*plan)
+	}
+	p, _ := plans.LoadOrStore(t, compilePlan(t))
+	return p.(*plan)
+}
+
+// compilePlan works out the plan for t. Types can only refer to themselves
+// through pointers, slices, maps, and the like, none of which are flat, so
+// only the types of array elements and struct fields are compiled
+// recursively; the rest are compiled when they are first needed.
+func compilePlan(t reflect.Type) *plan {
+	p := &plan{copier: lookupCopier(t)}
+	if p.copier == nil {
+		p.copier = builtins[t]
+	}
+	if p.copier == nil && t.Kind() != reflect.Interface && t.Implements(deepCopierType) {
+		p.copier = callDeepCopy
+		// Pointers get the DeepCopy methods of their elements too, but
+		// those return an element, not a pointer.
+		if t.Kind() == reflect.Ptr && t.Elem().Implements(deepCopierType) {
+			p.copier = callElemDeepCopy
+		}
+	}
+	if p.copier != nil {
+		return p
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		p.flat, p.flatUnexported = true, true
+	case reflect.Array:
+		elem := planFor(t.Elem())
+		p.flat, p.flatUnexported = elem.flat, elem.flatUnexported
+	case reflect.Struct:
+		p.flat, p.flatUnexported = true, true
+		p.fields = make([]fieldPlan, t.NumField())
+		for i := range p.fields {
+			f := t.Field(i)
+			fp := planFor(f.Type)
+			p.fields[i] = fieldPlan{index: i, name: f.Name, exported: f.IsExported(), tag: parseTag(f.Tag), structTag: f.Tag, plan: fp}
+			// Fields that aren't deep copied, or are skipped because they
+			// are unexported, can't be copied along with the rest.
+			if p.fields[i].tag.mode != deepField {
+				p.flat, p.flatUnexported = false, false
+			}
+			if !f.IsExported() {
+				p.flat = false
+			}
+			p.flat = p.flat && fp.flat
+			p.flatUnexported = p.flatUnexported && fp.flatUnexported
+		}
+	}
+	return p
+}
@@ -0,0 +1,205 @@
+package deepcopy
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlanFlat(t *testing.T) {
+	type flat struct {
+		A int
+		B [4]float64
+		C string
+	}
+	type unexported struct {
+		A int
+		b int
+	}
+	type tagged struct {
+		A int `deepcopy:"-"`
+	}
+	type nested struct {
+		F flat
+		U unexported
+	}
+	tests := []struct {
+		value          interface{}
+		flat           bool
+		flatUnexported bool
+	}{
+		{0, true, true},
+		{"", true, true},
+		{[3]int{}, true, true},
+		{[]int{}, false, false},
+		{map[string]int{}, false, false},
+		{new(int), false, false},
+		{flat{}, true, true},
+		{unexported{}, false, true},
+		{tagged{}, false, false},
+		{nested{}, false, true},
+		{[2]unexported{}, false, true},
+		{time.Time{}, false, false},
+		{sync.Mutex{}, false, false},
+	}
+	for i, test := range tests {
+		p := planFor(reflect.TypeOf(test.value))
+		if p.flat != test.flat {
+			t.Errorf("%d: %T: expected flat to be %t, got %t", i, test.value, test.flat, p.flat)
+		}
+		if p.flatUnexported != test.flatUnexported {
+			t.Errorf("%d: %T: expected flatUnexported to be %t, got %t", i, test.value, test.flatUnexported, p.flatUnexported)
+		}
+	}
+}
+
+// tree and table refer to themselves without going through a pointer.
+type tree []tree
+type table map[string]table
+
+func TestPlanRecursiveTypes(t *testing.T) {
+	tr := tree{{}, {{}, {}}}
+	cpy := Copy(tr)
+	if len(cpy) != 2 || len(cpy[1]) != 2 {
+		t.Errorf("expected %v, got %v", tr, cpy)
+	}
+	tb := table{"a": {"b": nil}}
+	tcpy := Copy(tb)
+	if _, ok := tcpy["a"]["b"]; !ok {
+		t.Errorf("expected %v, got %v", tb, tcpy)
+	}
+}
+
+// legacyIface is Iface as it was before copy plans, kept as a baseline for
+// the benchmarks.
+func legacyIface(iface interface{}) interface{} {
+	if iface == nil {
+		return nil
+	}
+	original := reflect.ValueOf(iface)
+	cpy := reflect.New(original.Type()).Elem()
+	legacyCopyRecursive(original, cpy)
+	return cpy.Interface()
+}
+
+func legacyCopyRecursive(original, cpy reflect.Value) {
+	switch original.Kind() {
+	case reflect.Ptr:
+		originalValue := original.Elem()
+		if !originalValue.IsValid() {
+			return
+		}
+		cpy.Set(reflect.New(originalValue.Type()))
+		legacyCopyRecursive(originalValue, cpy.Elem())
+	case reflect.Interface:
+		originalValue := original.Elem()
+		if !originalValue.IsValid() {
+			return
+		}
+		copyValue := reflect.New(originalValue.Type()).Elem()
+		legacyCopyRecursive(originalValue, copyValue)
+		cpy.Set(copyValue)
+	case reflect.Struct:
+		for i := 0; i < original.NumField(); i++ {
+			if cpy.Field(i).CanSet() {
+				legacyCopyRecursive(original.Field(i), cpy.Field(i))
+			}
+		}
+	case reflect.Slice:
+		cpy.Set(reflect.MakeSlice(original.Type(), original.Len(), original.Cap()))
+		for i := 0; i < original.Len(); i++ {
+			legacyCopyRecursive(original.Index(i), cpy.Index(i))
+		}
+	case reflect.Map:
+		cpy.Set(reflect.MakeMap(original.Type()))
+		for _, key := range original.MapKeys() {
+			originalValue := original.MapIndex(key)
+			copyValue := reflect.New(originalValue.Type()).Elem()
+			legacyCopyRecursive(originalValue, copyValue)
+			cpy.SetMapIndex(key, copyValue)
+		}
+	case reflect.String:
+		cpy.SetString(original.String())
+	case reflect.Int:
+		cpy.SetInt(original.Int())
+	case reflect.Bool:
+		cpy.SetBool(original.Bool())
+	case reflect.Float64:
+		cpy.SetFloat(original.Float())
+	default:
+		cpy.Set(original)
+	}
+}
+
+// requestContext is a typical request scoped struct that gets copied a lot.
+type requestContext struct {
+	ID      string
+	User    string
+	Roles   []string
+	Headers map[string][]string
+	Limits  struct {
+		Rate  int
+		Burst int
+	}
+	Trace *struct {
+		TraceID string
+		SpanID  string
+		Sampled bool
+	}
+}
+
+func newRequestContext() requestContext {
+	rc := requestContext{
+		ID:      "7d2c9b1e",
+		User:    "ford",
+		Roles:   []string{"admin", "user"},
+		Headers: map[string][]string{"Accept": {"application/json"}, "User-Agent": {"bench"}},
+	}
+	rc.Limits.Rate, rc.Limits.Burst = 100, 10
+	rc.Trace = &struct {
+		TraceID string
+		SpanID  string
+		Sampled bool
+	}{"a1b2", "c3d4", true}
+	return rc
+}
+
+type flatStruct struct {
+	A, B, C int64
+	D, E    float64
+	F       [8]int32
+	G       string
+}
+
+func benchmarkCopy(b *testing.B, fn func(interface{}) interface{}, v interface{}) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fn(v)
+	}
+}
+
+func makeInts(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+func makeFloats(n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = float64(i) * 1.5
+	}
+	return s
+}
+
+func BenchmarkIfaceInts(b *testing.B)          { benchmarkCopy(b, Iface, makeInts(1024)) }
+func BenchmarkLegacyIfaceInts(b *testing.B)    { benchmarkCopy(b, legacyIface, makeInts(1024)) }
+func BenchmarkIfaceFloats(b *testing.B)        { benchmarkCopy(b, Iface, makeFloats(1024)) }
+func BenchmarkLegacyIfaceFloats(b *testing.B)  { benchmarkCopy(b, legacyIface, makeFloats(1024)) }
+func BenchmarkIfaceFlat(b *testing.B)          { benchmarkCopy(b, Iface, make([]flatStruct, 64)) }
+func BenchmarkLegacyIfaceFlat(b *testing.B)    { benchmarkCopy(b, legacyIface, make([]flatStruct, 64)) }
+func BenchmarkIfaceRequest(b *testing.B)       { benchmarkCopy(b, Iface, newRequestContext()) }
+func BenchmarkLegacyIfaceRequest(b *testing.B) { benchmarkCopy(b, legacyIface, newRequestContext()) }
@@ -0,0 +1,210 @@
+package deepcopy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Project returns a deep copy of v that only has the values at paths, and
+// everything within them; everything else is left as its zero value. Paths
+// are written the way Path.String writes them, with "[*]" matching any
+// index or key, e.g. User.Name or Orders[*].Total. Map keys that are
+// strings can also be written as fields, so that User.Name selects the
+// "Name" entry of a map[string]interface{} held in User.
+//
+// Slices keep their length, with the elements that aren't selected left as
+// their zero value; maps only have the entries that are selected. Values
+// that cannot be deep copied are shared, as they are by Iface, and struct
+// fields are copied as their deepcopy tags say, even when they are
+// selected: fields tagged `deepcopy:"-"` are never copied, redacted ones are
+// left as their zero value, and shallow ones are shared. An error is
+// returned if a path can't match anything in the type of v, e.g. because a
+// field name is misspelled.
+func Project(v interface{}, paths ...string) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	original := reflect.ValueOf(v)
+	p := &projector{copier: newCopier(original.Type(), newConfig([]Option{OnUncopyable(Share)}))}
+	for _, path := range paths {
+		pt := compilePattern(path)
+		err := checkPattern(original.Type(), pt)
+		if err != nil {
+			return nil, fmt.Errorf("deepcopy: cannot project %s: %s", path, err)
+		}
+		p.patterns = append(p.patterns, pt)
+	}
+	cpy := reflect.New(original.Type()).Elem()
+	err := p.project(original, cpy)
+	if err != nil {
+		return nil, err
+	}
+	return cpy.Interface(), nil
+}
+
+// checkPattern returns an error if pt can't match anything in a value of
+// type t. What is held in interfaces is unknown, so anything matches it.
+func checkPattern(t reflect.Type, pt pattern) error {
+	for _, e := range pt {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Interface:
+			return nil
+		case e[0] == '[':
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			default:
+				return fmt.Errorf("%s has no index %s", t, e)
+			}
+		case t.Kind() == reflect.Struct:
+			f, ok := t.FieldByName(e)
+			if !ok || len(f.Index) != 1 || !f.IsExported() {
+				return fmt.Errorf("%s has no field %s", t, e)
+			}
+			t = f.Type
+		case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			t = t.Elem()
+		default:
+			return fmt.Errorf("%s has no field %s", t, e)
+		}
+	}
+	return nil
+}
+
+// projector holds the state of a single Project. The copier copies what is
+// selected; its path is the location being projected.
+type projector struct {
+	*copier
+	patterns []pattern
+}
+
+// match reports whether the current path is selected by one of the
+// patterns, and whether it is within one, leading to what is selected.
+func (p *projector) match() (selected, within bool) {
+	for _, pt := range p.patterns {
+		n := len(p.path)
+		if n > len(pt) {
+			n = len(pt)
+		}
+		matched := true
+		for i := 0; i < n && matched; i++ {
+			matched = projectElem(pt[i], p.path[i])
+		}
+		switch {
+		case !matched:
+		case len(p.path) >= len(pt):
+			return true, true
+		default:
+			within = true
+		}
+	}
+	return false, within
+}
+
+// projectElem reports whether the pattern element e matches s; a field name
+// also matches the same string key.
+func projectElem(e string, s Step) bool {
+	if matchElem(e, s) {
+		return true
+	}
+	return s.Kind == KeyStep && s.Key.Kind() == reflect.String && s.Key.String() == e
+}
+
+// project copies what is selected of original into cpy.
+func (p *projector) project(original, cpy reflect.Value) error {
+	selected, within := p.match()
+	if selected {
+		return p.copyRecursive(original, cpy)
+	}
+	if !within {
+		return nil
+	}
+	switch original.Kind() {
+	case reflect.Ptr:
+		if original.IsNil() {
+			return nil
+		}
+		ptr := reflect.New(original.Type().Elem())
+		cpy.Set(ptr)
+		return p.project(original.Elem(), ptr.Elem())
+	case reflect.Interface:
+		if original.IsNil() {
+			return nil
+		}
+		elem := reflect.New(original.Elem().Type()).Elem()
+		err := p.project(original.Elem(), elem)
+		if err != nil {
+			return err
+		}
+		cpy.Set(elem)
+	case reflect.Struct:
+		for _, f := range planFor(original.Type()).fields {
+			if !f.exported || f.tag.mode == skipField {
+				continue
+			}
+			// Fields that aren't deep copied are copied the way Iface copies
+			// them if anything in them is selected.
+			if f.tag.mode != deepField {
+				p.path = append(p.path, f.step())
+				if _, within := p.match(); within && f.tag.mode == shallowField {
+					cpy.Field(f.index).Set(original.Field(f.index))
+				} else if within {
+					p.redact(cpy.Field(f.index))
+				}
+				p.path = p.path[:len(p.path)-1]
+				continue
+			}
+			err := p.projectAt(f.step(), original.Field(f.index), cpy.Field(f.index))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if original.Kind() == reflect.Slice {
+			if original.IsNil() {
+				return nil
+			}
+			cpy.Set(reflect.MakeSlice(original.Type(), original.Len(), original.Len()))
+		}
+		for i := 0; i < original.Len(); i++ {
+			err := p.projectAt(Step{Kind: IndexStep, Index: i}, original.Index(i), cpy.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if original.IsNil() {
+			return nil
+		}
+		m := reflect.MakeMap(original.Type())
+		for iter := original.MapRange(); iter.Next(); {
+			p.path = append(p.path, Step{Kind: KeyStep, Key: iter.Key()})
+			if _, within := p.match(); within {
+				value := reflect.New(original.Type().Elem()).Elem()
+				err := p.project(iter.Value(), value)
+				if err != nil {
+					return err
+				}
+				m.SetMapIndex(iter.Key(), value)
+			}
+			p.path = p.path[:len(p.path)-1]
+		}
+		cpy.Set(m)
+	}
+	return nil
+}
+
+// projectAt projects original, which is at s within the current path, into
+// cpy.
+func (p *projector) projectAt(s Step, original, cpy reflect.Value) error {
+	p.path = append(p.path, s)
+	err := p.project(original, cpy)
+	if err != nil {
+		return err
+	}
+	p.path = p.path[:len(p.path)-1]
+	return nil
+}
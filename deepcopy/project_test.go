@@ -0,0 +1,130 @@
+package deepcopy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type projOrder struct {
+	ID    int
+	Total float64
+	Items []string
+}
+
+type projUser struct {
+	Name  string
+	Email string
+	Prefs map[string]interface{}
+}
+
+type projAccount struct {
+	User   *projUser
+	Orders []projOrder
+	Notes  map[string]string
+	Cache  []int `deepcopy:"-"`
+}
+
+func TestProject(t *testing.T) {
+	a := projAccount{
+		User: &projUser{
+			Name:  "ann",
+			Email: "ann@example.com",
+			Prefs: map[string]interface{}{"theme": "dark", "lang": "en", "ui": map[string]interface{}{"size": 2, "font": "mono"}},
+		},
+		Orders: []projOrder{{ID: 1, Total: 9.5, Items: []string{"a"}}, {ID: 2, Total: 3, Items: []string{"b"}}},
+		Notes:  map[string]string{"x": "1", "y": "2"},
+		Cache:  []int{1},
+	}
+	tests := []struct {
+		paths    []string
+		expected projAccount
+	}{
+		{nil, projAccount{}},
+		{[]string{"User.Name"}, projAccount{User: &projUser{Name: "ann"}}},
+		{
+			[]string{"Orders[*].Total", "Orders[1].ID"},
+			projAccount{Orders: []projOrder{{Total: 9.5}, {ID: 2, Total: 3}}},
+		},
+		{[]string{`Notes["y"]`}, projAccount{Notes: map[string]string{"y": "2"}}},
+		{
+			[]string{"User.Prefs.theme", `User.Prefs["ui"].size`},
+			projAccount{User: &projUser{Prefs: map[string]interface{}{"theme": "dark", "ui": map[string]interface{}{"size": 2}}}},
+		},
+		{[]string{"Orders"}, projAccount{Orders: a.Orders}},
+		{[]string{"Cache"}, projAccount{}},
+	}
+	for i, test := range tests {
+		got, err := Project(a, test.paths...)
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, got)
+		}
+		if shared := Shares(a, got); len(shared) != 0 {
+			t.Errorf("%d: expected the projection to share nothing, got %v", i, shared)
+		}
+	}
+
+	// Generic maps are projected to maps.
+	m := map[string]interface{}{"user": map[string]interface{}{"name": "ann", "age": 3}, "x": 1}
+	got, err := Project(m, "user.name")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]interface{}{"user": map[string]interface{}{"name": "ann"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestProjectTags(t *testing.T) {
+	type creds struct {
+		User     string
+		Password string `deepcopy:"redact"`
+	}
+	type shared struct {
+		N int
+		M int
+	}
+	type service struct {
+		Name   string
+		Secret string `deepcopy:"redact"`
+		Creds  creds
+		Shared *shared `deepcopy:"shallow"`
+	}
+	s := service{Name: "a", Secret: "s3cret", Creds: creds{User: "u", Password: "pw"}, Shared: &shared{1, 2}}
+	cpy, err := Project(s, "Secret", "Creds.Password", "Creds.User", "Shared.N")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := service{Creds: creds{User: "u"}, Shared: s.Shared}
+	got := cpy.(service)
+	if got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got.Shared != s.Shared {
+		t.Error("expected the shallow field to be shared")
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"User.Nmae", "deepcopy.projUser has no field Nmae"},
+		{"Orders.Total", "[]deepcopy.projOrder has no field Total"},
+		{"User[0]", "deepcopy.projUser has no index [0]"},
+		{"Orders[*].Items.Len", "[]string has no field Len"},
+		{"Cache.x", "[]int has no field x"},
+	}
+	for _, test := range tests {
+		_, err := Project(projAccount{}, test.path)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", test.path, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package deepcopy
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Shares returns the paths, within b, of the memory that b shares with a:
+// pointers, maps, and channels that a also reaches, and slices whose backing
+// arrays overlap one that a reaches. This holds wherever in a the shared
+// memory is, so Shares(v, Iface(v)) is empty if the copy is fully deep. Only
+// the outermost path to shared memory is returned, not the paths within it.
+//
+// Unexported fields are walked too. Funcs, strings, unsafe.Pointers, and the
+// standard library types that are never modified, e.g. time.Time, aren't
+// considered shared, as they can't be modified through the copy.
+func Shares(a, b interface{}) []Path {
+	var s sharer
+	s.visited = make(map[shareVisit]bool)
+	s.collect(reflect.ValueOf(a))
+	s.index()
+	s.visited = make(map[shareVisit]bool)
+	s.check(reflect.ValueOf(b))
+	return s.shared
+}
+
+// region is a range of memory, from start up to end.
+type region struct {
+	start, end uintptr
+}
+
+// shareVisit identifies memory that has already been walked. Slices that
+// start at the same place but have different lengths reach different
+// elements, so the length is part of it.
+type shareVisit struct {
+	visit
+	len int
+}
+
+// sharer holds the state of a single Shares.
+type sharer struct {
+	regions []region // the memory reachable from a
+	maxEnd  []uintptr
+	visited map[shareVisit]bool
+	path    Path
+	shared  []Path
+}
+
+// refRegion returns the memory that v, a pointer, map, slice, or channel,
+// refers to. It returns false if v refers to nothing that can be shared.
+func refRegion(v reflect.Value) (region, bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Type().Elem().Size() == 0 {
+			return region{}, false
+		}
+		return region{v.Pointer(), v.Pointer() + v.Type().Elem().Size()}, true
+	case reflect.Slice:
+		size := uintptr(v.Cap()) * v.Type().Elem().Size()
+		if v.IsNil() || size == 0 {
+			return region{}, false
+		}
+		return region{v.Pointer(), v.Pointer() + size}, true
+	case reflect.Map, reflect.Chan:
+		if v.IsNil() {
+			return region{}, false
+		}
+		return region{v.Pointer(), v.Pointer() + 1}, true
+	}
+	return region{}, false
+}
+
+// collect records the memory reachable from v.
+func (s *sharer) collect(v reflect.Value) {
+	s.walk(v, func(r region) bool {
+		s.regions = append(s.regions, r)
+		return true
+	})
+}
+
+// index sorts the regions so overlaps can be found with a binary search.
+// maxEnd[i] is the furthest end of regions[:i+1].
+func (s *sharer) index() {
+	sort.Slice(s.regions, func(i, j int) bool { return s.regions[i].start < s.regions[j].start })
+	s.maxEnd = make([]uintptr, len(s.regions))
+	var end uintptr
+	for i, r := range s.regions {
+		if r.end > end {
+			end = r.end
+		}
+		s.maxEnd[i] = end
+	}
+}
+
+// overlaps reports whether r overlaps any of the regions.
+func (s *sharer) overlaps(r region) bool {
+	// The regions that start before r ends overlap it if any of them ends
+	// after it starts.
+	n := sort.Search(len(s.regions), func(i int) bool { return s.regions[i].start >= r.end })
+	return n > 0 && s.maxEnd[n-1] > r.start
+}
+
+// check records the paths of the memory reachable from v that overlaps the
+// regions.
+func (s *sharer) check(v reflect.Value) {
+	s.walk(v, func(r region) bool {
+		if s.overlaps(r) {
+			s.shared = append(s.shared, s.path.clone())
+			return false
+		}
+		return true
+	})
+}
+
+// walk calls fn with the memory each pointer, map, slice, and channel
+// reachable from v refers to, and goes on into it if fn returns true.
+func (s *sharer) walk(v reflect.Value, fn func(region) bool) {
+	if !v.IsValid() || immutable[v.Type()] {
+		return
+	}
+	if r, ok := refRegion(v); ok {
+		if !fn(r) {
+			return
+		}
+		key := shareVisit{visit{ptr: v.Pointer(), typ: v.Type()}, 0}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if s.visited[key] {
+			return
+		}
+		s.visited[key] = true
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		s.walk(v.Elem(), fn)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			s.path = append(s.path, Step{Kind: FieldStep, Name: f.Name, Tag: f.Tag})
+			s.walk(v.Field(i), fn)
+			s.path = s.path[:len(s.path)-1]
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			s.path = append(s.path, Step{Kind: IndexStep, Index: i})
+			s.walk(v.Index(i), fn)
+			s.path = s.path[:len(s.path)-1]
+		}
+	case reflect.Map:
+		for _, key := range sortedKeys(v, v) {
+			s.path = append(s.path, Step{Kind: KeyStep, Key: key})
+			s.walk(key, fn)
+			s.walk(v.MapIndex(key), fn)
+			s.path = s.path[:len(s.path)-1]
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShares(t *testing.T) {
+	type node struct {
+		Name     string
+		Next     *node
+		Children []*node
+		Attrs    map[string]string
+		Events   chan int
+		Values   []int
+		When     time.Time
+		hidden   *int
+	}
+	n := 1
+	a := &node{
+		Name:     "a",
+		Children: []*node{{Name: "b"}, {Name: "c"}},
+		Attrs:    map[string]string{"k": "v"},
+		Events:   make(chan int),
+		Values:   []int{1, 2, 3},
+		When:     time.Now(),
+		hidden:   &n,
+	}
+	a.Next = a
+
+	if shared := Shares(a, Copy(a, CopyUnexported())); len(shared) != 1 || shared[0].String() != "Events" {
+		t.Errorf("expected only the channel to be shared, got %v", shared)
+	}
+
+	b := Copy(a, CopyUnexported())
+	b.Events = nil
+	b.Children[1] = a.Children[0]
+	b.Attrs = a.Attrs
+	b.Values = a.Values[2:]
+	b.hidden = &n
+	var paths []string
+	for _, p := range Shares(a, b) {
+		paths = append(paths, p.String())
+	}
+	expected := []string{"Children[1]", "Attrs", "Values", "hidden"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	if shared := Shares(a, a); len(shared) != 1 || shared[0].String() != "" {
+		t.Errorf("expected a value to share everything with itself, got %v", shared)
+	}
+}
+
+func TestSharesSlices(t *testing.T) {
+	backing := make([]int, 10)
+	tests := []struct {
+		a, b   interface{}
+		shared int
+	}{
+		{backing[:5], backing[5:], 1},   // within the capacity of a
+		{backing[:5:5], backing[5:], 0}, // past the capacity of a
+		{[]struct{}{{}}, []struct{}{{}}, 0},
+		{&backing[3], backing[2:4], 1},
+		{[]interface{}{backing}, map[string][]int{"x": backing[9:]}, 1},
+		{nil, backing, 0},
+	}
+	for i, test := range tests {
+		if shared := Shares(test.a, test.b); len(shared) != test.shared {
+			t.Errorf("%d: expected %d shared, got %v", i, test.shared, shared)
+		}
+	}
+
+	// A slice that contains itself.
+	s := []interface{}{nil}
+	s[0] = s
+	if shared := Shares(s, []interface{}{s}); len(shared) != 1 || shared[0].String() != "[0]" {
+		t.Errorf("expected the self referencing slice to be shared, got %v", shared)
+	}
+}
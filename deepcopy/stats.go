@@ -0,0 +1,95 @@
+package deepcopy
+
+import (
+	"reflect"
+	"sync"
+)
+
+// maxLargest is how many of the largest subtrees Stats keeps.
+const maxLargest = 10
+
+// Stats are what WithStats records about a copy.
+type Stats struct {
+	Nodes    int                  // how many values were copied
+	Allocs   map[reflect.Kind]int // the allocations made for the copy, by the kind of value they were made for
+	Bytes    int                  // the bytes allocated for the copy; those for maps are estimated
+	MaxDepth int                  // the length of the longest path copied
+	// Largest are the ten largest subtrees of the value, by Bytes and then
+	// by Nodes, largest first. The value as a whole isn't among them, but a
+	// subtree and those within it can be. A pointer or interface and the
+	// value it holds are the same subtree.
+	Largest []Subtree
+}
+
+// Subtree is a value within the value being copied and everything within
+// it.
+type Subtree struct {
+	Path  Path // where the subtree is
+	Nodes int  // how many values in the subtree were copied
+	Bytes int  // the bytes allocated for the subtree's copy
+}
+
+// tally is what a single copier has copied, to work out the size of the
+// subtrees it copies.
+type tally struct {
+	nodes, bytes int
+}
+
+// tracer records the Stats of a copy. It is shared by the workers of a
+// parallel copy.
+type tracer struct {
+	mu    sync.Mutex
+	stats *Stats
+}
+
+// alloc records an allocation of n bytes for a value of kind k.
+func (c *copier) alloc(k reflect.Kind, n int) {
+	if c.trace == nil {
+		return
+	}
+	c.traced.bytes += n
+	t := c.trace
+	t.mu.Lock()
+	t.stats.Allocs[k]++
+	t.stats.Bytes += n
+	t.mu.Unlock()
+}
+
+// traceNode records the value at depth within c's path that has just been
+// copied. before is what c had copied before it, and subtree is whether it
+// can be one of the largest subtrees.
+func (c *copier) traceNode(depth int, before tally, subtree bool) {
+	sub := Subtree{Nodes: c.traced.nodes - before.nodes, Bytes: c.traced.bytes - before.bytes}
+	t := c.trace
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.stats
+	s.Nodes++
+	if depth > s.MaxDepth {
+		s.MaxDepth = depth
+	}
+	if depth == 0 || !subtree {
+		return
+	}
+	i := len(s.Largest)
+	for i > 0 && larger(sub, s.Largest[i-1]) {
+		i--
+	}
+	if i == maxLargest {
+		return
+	}
+	sub.Path = c.path[:depth].clone()
+	if len(s.Largest) < maxLargest {
+		s.Largest = append(s.Largest, Subtree{})
+	}
+	copy(s.Largest[i+1:], s.Largest[i:])
+	s.Largest[i] = sub
+}
+
+// larger reports whether a is larger than b, by Bytes and then by Nodes.
+func larger(a, b Subtree) bool {
+	if a.Bytes != b.Bytes {
+		return a.Bytes > b.Bytes
+	}
+	return a.Nodes > b.Nodes
+}
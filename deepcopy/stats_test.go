@@ -0,0 +1,85 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	type leaf struct {
+		Name string
+	}
+	type tree struct {
+		Small *leaf
+		Big   []*leaf
+		Data  []byte
+	}
+	v := &tree{
+		Small: &leaf{"s"},
+		Big:   []*leaf{{"a"}, {"b"}, {"c"}},
+		Data:  make([]byte, 100),
+	}
+	for _, opts := range [][]Option{nil, {Parallel(2, 2)}} {
+		var s Stats
+		cpy, err := IfaceE(v, append(opts, WithStats(&s))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(v, cpy) {
+			t.Errorf("expected an equal copy, got %+v", cpy)
+		}
+		// The pointer to the tree, the tree, Small and its leaf, Big, each
+		// of its pointers and their leaves, and Data.
+		if s.Nodes != 12 {
+			t.Errorf("expected 12 nodes, got %d", s.Nodes)
+		}
+		expected := map[reflect.Kind]int{reflect.Ptr: 5, reflect.Slice: 2}
+		if !reflect.DeepEqual(s.Allocs, expected) {
+			t.Errorf("expected allocations %v, got %v", expected, s.Allocs)
+		}
+		// The tree, 4 leaves, 3 pointers, and 100 bytes.
+		size := int(reflect.TypeOf(tree{}).Size() + 4*reflect.TypeOf(leaf{}).Size() + 3*reflect.TypeOf(&leaf{}).Size() + 100)
+		if s.Bytes != size {
+			t.Errorf("expected %d bytes, got %d", size, s.Bytes)
+		}
+		if s.MaxDepth != 2 {
+			t.Errorf("expected a depth of 2, got %d", s.MaxDepth)
+		}
+		// Small, Big, each of its elements, and Data.
+		if len(s.Largest) != 6 {
+			t.Fatalf("expected 6 subtrees, got %d", len(s.Largest))
+		}
+		big := int(3*reflect.TypeOf(leaf{}).Size() + 3*reflect.TypeOf(&leaf{}).Size())
+		for i, sub := range []Subtree{{Path{{Kind: FieldStep, Name: "Data"}}, 1, 100}, {Path{{Kind: FieldStep, Name: "Big"}}, 7, big}} {
+			got := s.Largest[i]
+			if got.Path.String() != sub.Path.String() || got.Nodes != sub.Nodes || got.Bytes != sub.Bytes {
+				t.Errorf("%d: expected %s with %d nodes and %d bytes, got %s with %d and %d", i, sub.Path, sub.Nodes, sub.Bytes, got.Path, got.Nodes, got.Bytes)
+			}
+		}
+	}
+}
+
+func TestStatsLargestPaths(t *testing.T) {
+	type big struct {
+		B []byte
+	}
+	type outer struct {
+		A *big
+		I interface{}
+	}
+	var s Stats
+	_, err := IfaceE(outer{A: &big{make([]byte, 10)}, I: &big{make([]byte, 20)}}, WithStats(&s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, sub := range s.Largest {
+		paths = append(paths, sub.Path.String())
+	}
+	// The pointers, and the values they point to, are at the same path, and
+	// only the outermost is kept.
+	expected := []string{"I", "A", "I.B", "A.B"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected the largest subtrees at %v, got %v", expected, paths)
+	}
+}
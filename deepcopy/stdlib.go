@@ -0,0 +1,80 @@
+package deepcopy
+
+import (
+	"math/big"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// builtins are the copiers for standard library types whose state is held
+// in unexported fields, which would otherwise be lost or, when copying
+// unexported fields, shared with the original. Registered copiers take
+// precedence over these.
+var builtins = map[reflect.Type]CopierFunc{
+	// Arbitrary precision numbers own their backing slices.
+	reflect.TypeOf(big.Int{}): func(src reflect.Value) (reflect.Value, error) {
+		x := src.Interface().(big.Int)
+		var z big.Int
+		z.Set(&x)
+		return reflect.ValueOf(&z).Elem(), nil
+	},
+	reflect.TypeOf(big.Float{}): func(src reflect.Value) (reflect.Value, error) {
+		x := src.Interface().(big.Float)
+		var z big.Float
+		z.Copy(&x)
+		return reflect.ValueOf(&z).Elem(), nil
+	},
+	reflect.TypeOf(big.Rat{}): func(src reflect.Value) (reflect.Value, error) {
+		x := src.Interface().(big.Rat)
+		var z big.Rat
+		z.Set(&x)
+		return reflect.ValueOf(&z).Elem(), nil
+	},
+	// Copying the state of a sync primitive, e.g. a locked mutex, is never
+	// what is wanted: the copy gets a new one.
+	reflect.TypeOf(sync.Mutex{}):     zeroValue,
+	reflect.TypeOf(sync.RWMutex{}):   zeroValue,
+	reflect.TypeOf(sync.WaitGroup{}): zeroValue,
+	reflect.TypeOf(sync.Once{}):      zeroValue,
+}
+
+// locks are the sync primitives, whose state belongs to the value holding
+// them.
+var locks = map[reflect.Type]bool{
+	reflect.TypeOf(sync.Mutex{}):     true,
+	reflect.TypeOf(sync.RWMutex{}):   true,
+	reflect.TypeOf(sync.WaitGroup{}): true,
+	reflect.TypeOf(sync.Once{}):      true,
+}
+
+// immutable are the standard library types whose values, and whatever they
+// point to, are never modified, so a copy of the value is a deep copy. They
+// are copied with copyValue and can be shared safely.
+var immutable = map[reflect.Type]bool{
+	reflect.TypeOf(time.Time{}):      true,
+	reflect.TypeOf(url.Userinfo{}):   true,
+	reflect.TypeOf(netip.Addr{}):     true,
+	reflect.TypeOf(netip.AddrPort{}): true,
+	reflect.TypeOf(netip.Prefix{}):   true,
+	// Locations are never modified once loaded.
+	reflect.TypeOf(&time.Location{}): true,
+}
+
+func init() {
+	for t := range immutable {
+		builtins[t] = copyValue
+	}
+}
+
+// copyValue is the CopierFunc for types whose values can be copied as is.
+func copyValue(src reflect.Value) (reflect.Value, error) {
+	return src, nil
+}
+
+// zeroValue is the CopierFunc for types whose values are reset in the copy.
+func zeroValue(src reflect.Value) (reflect.Value, error) {
+	return reflect.Zero(src.Type()), nil
+}
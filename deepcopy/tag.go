@@ -0,0 +1,58 @@
+package deepcopy
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagKey is the key of the struct tag that controls how a field is copied.
+const tagKey = "deepcopy"
+
+// fieldMode is how a struct field is copied.
+type fieldMode int
+
+const (
+	// deepField is deep copied; this is the default.
+	deepField fieldMode = iota
+	// skipField is left as its zero value: `deepcopy:"-"`.
+	skipField
+	// shallowField is set to the original value: `deepcopy:"shallow"`.
+	shallowField
+	// redactField is set to its zero value or, for strings, the redaction
+	// placeholder: `deepcopy:"redact"`.
+	redactField
+)
+
+// fieldTag is a parsed deepcopy struct tag. The tag value is a comma
+// separated list of options; unknown options are ignored.
+type fieldTag struct {
+	mode fieldMode
+	// name is the name the field is matched by when converting between
+	// struct types: `deepcopy:"name=ID"`.
+	name string
+}
+
+// parseTag parses the deepcopy tag of a struct field.
+func parseTag(tag reflect.StructTag) fieldTag {
+	var ft fieldTag
+	s, ok := tag.Lookup(tagKey)
+	if !ok {
+		return ft
+	}
+	for _, opt := range strings.Split(s, ",") {
+		opt = strings.TrimSpace(opt)
+		if name, ok := strings.CutPrefix(opt, "name="); ok {
+			ft.name = name
+			continue
+		}
+		switch opt {
+		case "-":
+			ft.mode = skipField
+		case "shallow":
+			ft.mode = shallowField
+		case "redact":
+			ft.mode = redactField
+		}
+	}
+	return ft
+}
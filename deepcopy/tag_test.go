@@ -0,0 +1,85 @@
+package deepcopy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag      reflect.StructTag
+		expected fieldMode
+		name     string
+	}{
+		{``, deepField, ""},
+		{`json:"name"`, deepField, ""},
+		{`deepcopy:""`, deepField, ""},
+		{`deepcopy:"-"`, skipField, ""},
+		{`deepcopy:"shallow"`, shallowField, ""},
+		{`deepcopy:"redact"`, redactField, ""},
+		{`json:"-" deepcopy:"unknown,redact"`, redactField, ""},
+		{`deepcopy:"name=ID"`, deepField, "ID"},
+		{`deepcopy:"shallow, name=Log"`, shallowField, "Log"},
+	}
+	for i, test := range tests {
+		ft := parseTag(test.tag)
+		if ft.mode != test.expected {
+			t.Errorf("%d: expected mode %d, got %d", i, test.expected, ft.mode)
+		}
+		if ft.name != test.name {
+			t.Errorf("%d: expected name %q, got %q", i, test.name, ft.name)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	type logger struct {
+		Prefix string
+	}
+	type config struct {
+		Name     string
+		Cache    map[string]int `deepcopy:"-"`
+		Log      *logger        `deepcopy:"shallow"`
+		Password string         `deepcopy:"redact"`
+		Token    []byte         `deepcopy:"redact"`
+		secret   string         `deepcopy:"redact"`
+		hidden   string         `deepcopy:"shallow"`
+	}
+	l := &logger{Prefix: "cfg: "}
+	cfg := config{
+		Name:     "cfg",
+		Cache:    map[string]int{"a": 1},
+		Log:      l,
+		Password: "hunter2",
+		Token:    []byte("abc"),
+		secret:   "shh",
+		hidden:   "h",
+	}
+	cpy := Copy(cfg)
+	if cpy.Name != "cfg" {
+		t.Errorf("expected name to be \"cfg\", got %q", cpy.Name)
+	}
+	if cpy.Cache != nil {
+		t.Errorf("expected the cache to be skipped, got %v", cpy.Cache)
+	}
+	if cpy.Log != l {
+		t.Error("expected the logger to be shared")
+	}
+	if cpy.Password != "" || cpy.Token != nil {
+		t.Errorf("expected the password and token to be redacted, got %q and %q", cpy.Password, cpy.Token)
+	}
+	if cpy.secret != "" || cpy.hidden != "" {
+		t.Error("expected unexported fields to not be copied")
+	}
+
+	cpy = Copy(cfg, CopyUnexported(), RedactPlaceholder("[REDACTED]"))
+	if cpy.Password != "[REDACTED]" || cpy.secret != "[REDACTED]" {
+		t.Errorf("expected the password and secret to be the placeholder, got %q and %q", cpy.Password, cpy.secret)
+	}
+	if cpy.Token != nil {
+		t.Errorf("expected the token to be redacted, got %q", cpy.Token)
+	}
+	if cpy.hidden != "h" {
+		t.Errorf("expected hidden to be shared, got %q", cpy.hidden)
+	}
+}